@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Search analytics keep aggregate counters per normalised query. Nothing that
+// identifies a visitor (IP, user agent, cookies) is ever recorded.
+const (
+	analyticsMaxQueries  = 1000
+	analyticsMaxQueryLen = 100
+	analyticsTrendDays   = 7
+	// analyticsMinReportCount keeps one-off queries, which are the most likely
+	// to contain personal data, out of every report.
+	analyticsMinReportCount = 2
+)
+
+// queryStat aggregates every occurrence of one query on one endpoint.
+type queryStat struct {
+	Endpoint      string         `json:"endpoint"`
+	Query         string         `json:"query"`
+	Count         int            `json:"count"`
+	ZeroResults   int            `json:"zeroResults"`
+	TotalResults  int            `json:"totalResults"`
+	TotalLatencyU int64          `json:"totalLatencyMicros"`
+	LastSeen      time.Time      `json:"lastSeen"`
+	Days          map[string]int `json:"days"`
+}
+
+// searchAnalytics is a bounded in-memory store of query statistics. When full,
+// the least recently seen query is evicted.
+type searchAnalytics struct {
+	mu    sync.Mutex
+	stats map[string]*queryStat
+	dirty bool
+}
+
+func newSearchAnalytics() *searchAnalytics {
+	return &searchAnalytics{stats: make(map[string]*queryStat)}
+}
+
+// normalizeAnalyticsQuery folds the query parts the same way the filters do and
+// caps the length, so equivalent searches share a counter.
+func normalizeAnalyticsQuery(parts ...string) string {
+	words := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = normalizeText(p); p != "" {
+			words = append(words, p)
+		}
+	}
+	return truncateQuery(strings.Join(words, " "))
+}
+
+// analyticsQueryText lower-cases a structured query and collapses its spaces.
+func analyticsQueryText(query string) string {
+	return truncateQuery(strings.Join(strings.Fields(strings.ToLower(query)), " "))
+}
+
+func truncateQuery(query string) string {
+	runes := []rune(query)
+	if len(runes) > analyticsMaxQueryLen {
+		runes = runes[:analyticsMaxQueryLen]
+	}
+	return strings.TrimSpace(string(runes))
+}
+
+// Record counts one search. Empty queries are ignored.
+func (s *searchAnalytics) Record(endpoint, query string, results int, latency time.Duration, now time.Time) {
+	if s == nil || query == "" {
+		return
+	}
+	key := endpoint + "\x00" + query
+	day := now.UTC().Format("2006-01-02")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[key]
+	if !ok {
+		if len(s.stats) >= analyticsMaxQueries {
+			s.evictOldest()
+		}
+		st = &queryStat{Endpoint: endpoint, Query: query, Days: make(map[string]int)}
+		s.stats[key] = st
+	}
+	st.Count++
+	if results == 0 {
+		st.ZeroResults++
+	}
+	st.TotalResults += results
+	st.TotalLatencyU += latency.Microseconds()
+	st.LastSeen = now.UTC()
+	st.Days[day]++
+	cutoff := now.UTC().AddDate(0, 0, -2*analyticsTrendDays).Format("2006-01-02")
+	for d := range st.Days {
+		if d <= cutoff {
+			delete(st.Days, d)
+		}
+	}
+	s.dirty = true
+}
+
+func (s *searchAnalytics) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, st := range s.stats {
+		if oldestKey == "" || st.LastSeen.Before(oldest) {
+			oldestKey, oldest = key, st.LastSeen
+		}
+	}
+	delete(s.stats, oldestKey)
+}
+
+// QueryReport is the public view of a query in the admin report.
+type QueryReport struct {
+	Endpoint     string    `json:"endpoint"`
+	Query        string    `json:"query"`
+	Count        int       `json:"count"`
+	ZeroResults  int       `json:"zeroResults"`
+	AvgResults   float64   `json:"avgResults"`
+	AvgLatencyMs float64   `json:"avgLatencyMs"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+// TrendReport compares the last analyticsTrendDays days with the days before.
+type TrendReport struct {
+	Endpoint string `json:"endpoint"`
+	Query    string `json:"query"`
+	Recent   int    `json:"recent"`
+	Previous int    `json:"previous"`
+}
+
+// AnalyticsReport is the payload of the popular-queries admin endpoint.
+type AnalyticsReport struct {
+	Tracked     int           `json:"tracked"`
+	Top         []QueryReport `json:"top"`
+	ZeroResults []QueryReport `json:"zeroResults"`
+	Trending    []TrendReport `json:"trending"`
+}
+
+func (st *queryStat) report() QueryReport {
+	return QueryReport{
+		Endpoint:     st.Endpoint,
+		Query:        st.Query,
+		Count:        st.Count,
+		ZeroResults:  st.ZeroResults,
+		AvgResults:   float64(st.TotalResults) / float64(st.Count),
+		AvgLatencyMs: float64(st.TotalLatencyU) / float64(st.Count) / 1000,
+		LastSeen:     st.LastSeen,
+	}
+}
+
+// trend sums the daily counts of the recent and the previous window.
+func (st *queryStat) trend(now time.Time) (recent, previous int) {
+	recentFrom := now.UTC().AddDate(0, 0, -analyticsTrendDays).Format("2006-01-02")
+	for day, n := range st.Days {
+		if day > recentFrom {
+			recent += n
+		} else {
+			previous += n
+		}
+	}
+	return recent, previous
+}
+
+// Report lists the most frequent queries, the ones that most often return
+// nothing and the ones gaining popularity. endpoint restricts the report when set.
+func (s *searchAnalytics) Report(endpoint string, limit int, now time.Time) AnalyticsReport {
+	report := AnalyticsReport{Top: []QueryReport{}, ZeroResults: []QueryReport{}, Trending: []TrendReport{}}
+	if s == nil {
+		return report
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, st := range s.stats {
+		if endpoint != "" && st.Endpoint != endpoint {
+			continue
+		}
+		report.Tracked++
+		if st.Count < analyticsMinReportCount {
+			continue
+		}
+		report.Top = append(report.Top, st.report())
+		if st.ZeroResults > 0 {
+			report.ZeroResults = append(report.ZeroResults, st.report())
+		}
+		if recent, previous := st.trend(now); recent > previous {
+			report.Trending = append(report.Trending, TrendReport{Endpoint: st.Endpoint, Query: st.Query, Recent: recent, Previous: previous})
+		}
+	}
+
+	byCount := func(list []QueryReport, count func(QueryReport) int) {
+		sort.Slice(list, func(i, j int) bool {
+			if count(list[i]) != count(list[j]) {
+				return count(list[i]) > count(list[j])
+			}
+			if list[i].Query != list[j].Query {
+				return list[i].Query < list[j].Query
+			}
+			return list[i].Endpoint < list[j].Endpoint
+		})
+	}
+	byCount(report.Top, func(q QueryReport) int { return q.Count })
+	byCount(report.ZeroResults, func(q QueryReport) int { return q.ZeroResults })
+	sort.Slice(report.Trending, func(i, j int) bool {
+		gi := report.Trending[i].Recent - report.Trending[i].Previous
+		gj := report.Trending[j].Recent - report.Trending[j].Previous
+		if gi != gj {
+			return gi > gj
+		}
+		return report.Trending[i].Query < report.Trending[j].Query
+	})
+
+	if len(report.Top) > limit {
+		report.Top = report.Top[:limit]
+	}
+	if len(report.ZeroResults) > limit {
+		report.ZeroResults = report.ZeroResults[:limit]
+	}
+	if len(report.Trending) > limit {
+		report.Trending = report.Trending[:limit]
+	}
+	return report
+}
+
+// Load restores statistics saved by Save. A missing file is not an error.
+func (s *searchAnalytics) Load(path string) error {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []*queryStat
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range saved {
+		if st.Query == "" || st.Count <= 0 {
+			continue
+		}
+		if st.Days == nil {
+			st.Days = make(map[string]int)
+		}
+		s.stats[st.Endpoint+"\x00"+st.Query] = st
+	}
+	for len(s.stats) > analyticsMaxQueries {
+		s.evictOldest()
+	}
+	return nil
+}
+
+// Save writes the statistics to path atomically when they changed since the last save.
+func (s *searchAnalytics) Save(path string) error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	saved := make([]queryStat, 0, len(s.stats))
+	for _, st := range s.stats {
+		cp := *st
+		cp.Days = make(map[string]int, len(st.Days))
+		for d, n := range st.Days {
+			cp.Days[d] = n
+		}
+		saved = append(saved, cp)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	raw, err := json.Marshal(saved)
+	if err == nil {
+		tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+		if err = os.WriteFile(tmp, raw, 0o600); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// persistEvery saves the statistics to path on every tick.
+func (s *searchAnalytics) persistEvery(path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.Save(path); err != nil {
+			log.Printf("save search analytics: %v", err)
+		}
+	}
+}
+
+// recordSearch adds a search to the analytics, measuring latency from started.
+func (a *App) recordSearch(endpoint, query string, results int, started time.Time) {
+	a.analytics.Record(endpoint, query, results, time.Since(started), a.currentTime())
+}
+
+// handleAdminSearchStats reports popular, zero-result and trending queries.
+// It requires the admin token as a bearer token and is disabled without one.
+func (a *App) handleAdminSearchStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	if a.adminToken == "" {
+		writeProblem(w, r, codeAdminDisabled)
+		return
+	}
+	token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !bearer || subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeProblem(w, r, codeAdminUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	limit := 20
+	if limitStr := strings.TrimSpace(q.Get("limit")); limitStr != "" {
+		v, err := strconv.Atoi(limitStr)
+		if err != nil || v <= 0 || v > 100 {
+			writeParamError(w, r, newParamError("limit", codeInvalidLimit))
+			return
+		}
+		limit = v
+	}
+	endpoint := strings.TrimSpace(q.Get("endpoint"))
+	writeJSON(w, http.StatusOK, a.analytics.Report(endpoint, limit, a.currentTime()))
+}
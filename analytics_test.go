@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSearchAnalyticsReport(t *testing.T) {
+	s := newSearchAnalytics()
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -10)
+
+	s.Record("artists", normalizeAnalyticsQuery("Queen"), 1, time.Millisecond, old)
+	s.Record("artists", normalizeAnalyticsQuery("queen "), 1, time.Millisecond, now)
+	s.Record("artists", normalizeAnalyticsQuery("QUEEN"), 1, time.Millisecond, now)
+	s.Record("search", normalizeAnalyticsQuery("Beyoncé"), 0, time.Millisecond, now)
+	s.Record("search", normalizeAnalyticsQuery("beyonce"), 0, time.Millisecond, now)
+	s.Record("search", normalizeAnalyticsQuery("jean dupont"), 0, time.Millisecond, now)
+
+	report := s.Report("", 10, now)
+	if report.Tracked != 3 {
+		t.Fatalf("expected 3 tracked queries, got %d", report.Tracked)
+	}
+	if len(report.Top) != 2 || report.Top[0].Query != "queen" || report.Top[0].Count != 3 {
+		t.Fatalf("unexpected top queries %+v", report.Top)
+	}
+	if len(report.ZeroResults) != 1 || report.ZeroResults[0].Query != "beyonce" {
+		t.Fatalf("unexpected zero-result queries %+v", report.ZeroResults)
+	}
+	for _, q := range report.Top {
+		if q.Query == "jean dupont" {
+			t.Fatal("a query seen once must not be reported")
+		}
+	}
+	if len(report.Trending) != 2 || report.Trending[0].Query != "beyonce" {
+		t.Fatalf("expected new queries to trend first, got %+v", report.Trending)
+	}
+	if q := report.Trending[1]; q.Query != "queen" || q.Recent != 2 || q.Previous != 1 {
+		t.Fatalf("unexpected trend %+v", q)
+	}
+}
+
+func TestSearchAnalyticsIsBounded(t *testing.T) {
+	s := newSearchAnalytics()
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < analyticsMaxQueries+10; i++ {
+		s.Record("search", normalizeAnalyticsQuery("q", string(rune('a'+i%26)), time.Duration(i).String()), 1, 0, now.Add(time.Duration(i)*time.Second))
+	}
+	if len(s.stats) != analyticsMaxQueries {
+		t.Fatalf("expected %d entries, got %d", analyticsMaxQueries, len(s.stats))
+	}
+}
+
+func TestSearchAnalyticsPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analytics.json")
+	now := time.Date(2024, 6, 20, 12, 0, 0, 0, time.UTC)
+	s := newSearchAnalytics()
+	s.Record("events", "london", 4, time.Millisecond, now)
+	s.Record("events", "london", 4, time.Millisecond, now)
+	if err := s.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	restored := newSearchAnalytics()
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	report := restored.Report("events", 10, now)
+	if len(report.Top) != 1 || report.Top[0].Count != 2 || report.Top[0].AvgResults != 4 {
+		t.Fatalf("unexpected restored report %+v", report.Top)
+	}
+}
+
+func TestAdminSearchStatsEndpoint(t *testing.T) {
+	app := newTestApp()
+	app.analytics = newSearchAnalytics()
+	app.cache.Set(DataBundle{Artists: []Artist{{ID: 1, Name: "Queen"}}})
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		app.handleAPIArtists(rr, httptest.NewRequest(http.MethodGet, "/api/artists?name=Nobody", nil))
+	}
+
+	rr := httptest.NewRecorder()
+	app.handleAdminSearchStats(rr, httptest.NewRequest(http.MethodGet, "/api/admin/search-stats", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a configured token, got %d", rr.Code)
+	}
+
+	app.adminToken = "secret"
+	for _, header := range []string{"Bearer wrong", "secret", "Basic secret"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/search-stats", nil)
+		req.Header.Set("Authorization", header)
+		rr = httptest.NewRecorder()
+		app.handleAdminSearchStats(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for Authorization %q, got %d", header, rr.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/search-stats", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr = httptest.NewRecorder()
+	app.handleAdminSearchStats(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	var report AnalyticsReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(report.ZeroResults) != 1 || report.ZeroResults[0].Query != "nobody" || report.ZeroResults[0].Endpoint != "artists" {
+		t.Fatalf("unexpected report %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The /api/v2 namespace wraps every response in the same envelope and gives
+// every resource a type and a string ID. /api stays frozen as v1.
+
+const apiV2Prefix = "/api/v2"
+
+// v2Envelope is the body of every successful v2 response.
+type v2Envelope struct {
+	Data  interface{} `json:"data"`
+	Meta  v2Meta      `json:"meta"`
+	Links v2Links     `json:"links"`
+}
+
+// v2Meta describes the response. Total and Count are only set on lists.
+type v2Meta struct {
+	APIVersion string                  `json:"apiVersion"`
+	Version    string                  `json:"version"`
+	Total      *int                    `json:"total,omitempty"`
+	Count      *int                    `json:"count,omitempty"`
+	Facets     map[string][]FacetValue `json:"facets,omitempty"`
+}
+
+type v2Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+}
+
+// artistV2 is the single artist shape of v2, whatever the source. IDs are
+// prefixed with the source, e.g. groupie:1 or spotify:0OdUWJ0sBjDrqHygGUXeCF.
+type artistV2 struct {
+	Type              string   `json:"type"`
+	ID                string   `json:"id"`
+	Source            string   `json:"source"`
+	Slug              string   `json:"slug,omitempty"`
+	Name              string   `json:"name"`
+	Image             string   `json:"image,omitempty"`
+	Members           []string `json:"members"`
+	CreationDate      int      `json:"creationDate,omitempty"`
+	FirstAlbum        string   `json:"firstAlbum,omitempty"`
+	FirstAlbumYear    int      `json:"firstAlbumYear,omitempty"`
+	YearsToFirstAlbum *int     `json:"yearsToFirstAlbum,omitempty"`
+	ConcertCount      int      `json:"concertCount"`
+	Genres            []string `json:"genres,omitempty"`
+	Popularity        *int     `json:"popularity,omitempty"`
+	Followers         *int     `json:"followers,omitempty"`
+	MatchScore        *float64 `json:"matchScore,omitempty"`
+}
+
+type memberBandV2 struct {
+	ArtistID   string `json:"artistId"`
+	ArtistName string `json:"artistName"`
+}
+
+type memberV2 struct {
+	Type  string         `json:"type"`
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Bands []memberBandV2 `json:"bands"`
+}
+
+type locationV2 struct {
+	Type        string `json:"type"`
+	ID          string `json:"id"`
+	ArtistID    string `json:"artistId"`
+	ArtistName  string `json:"artistName"`
+	Slug        string `json:"slug"`
+	City        string `json:"city"`
+	Country     string `json:"country"`
+	CountryCode string `json:"countryCode"`
+	EventCount  int    `json:"eventCount"`
+}
+
+type eventV2 struct {
+	Type       string    `json:"type"`
+	ID         string    `json:"id"`
+	ArtistID   string    `json:"artistId"`
+	ArtistName string    `json:"artistName"`
+	Location   string    `json:"location"`
+	City       string    `json:"city"`
+	Country    string    `json:"country"`
+	Date       string    `json:"date"`
+	LocalDate  string    `json:"localDate"`
+	TimeZone   string    `json:"timeZone"`
+	StartsAt   time.Time `json:"startsAt"`
+}
+
+func groupieArtistRef(id int) string {
+	return sourceGroupie + ":" + strconv.Itoa(id)
+}
+
+func toArtistV2(art ArtistWithMeta) artistV2 {
+	concerts := 0
+	for _, dates := range art.DatesLocations {
+		concerts += len(dates)
+	}
+	return artistV2{
+		Type:              "artist",
+		ID:                groupieArtistRef(art.ID),
+		Source:            sourceGroupie,
+		Slug:              art.Slug,
+		Name:              art.Name,
+		Image:             art.Image,
+		Members:           append([]string{}, art.Members...),
+		CreationDate:      art.CreationDate,
+		FirstAlbum:        art.FirstAlbumISO,
+		FirstAlbumYear:    art.FirstAlbumYear,
+		YearsToFirstAlbum: art.YearsToFirstAlbum,
+		ConcertCount:      concerts,
+		MatchScore:        art.MatchScore,
+	}
+}
+
+func spotifyArtistV2(id, name, image string, genres []string, popularity int) artistV2 {
+	return artistV2{
+		Type:       "artist",
+		ID:         sourceSpotify + ":" + id,
+		Source:     sourceSpotify,
+		Name:       name,
+		Image:      image,
+		Members:    []string{},
+		Genres:     genres,
+		Popularity: &popularity,
+	}
+}
+
+func toMemberV2(m Member) memberV2 {
+	bands := make([]memberBandV2, 0, len(m.Bands))
+	for _, b := range m.Bands {
+		bands = append(bands, memberBandV2{ArtistID: groupieArtistRef(b.ArtistID), ArtistName: b.ArtistName})
+	}
+	return memberV2{Type: "member", ID: m.ID, Name: m.Name, Bands: bands}
+}
+
+func toLocationV2(l viewLocation) locationV2 {
+	return locationV2{
+		Type:        "location",
+		ID:          groupieArtistRef(l.ArtistID) + ":" + l.Raw,
+		ArtistID:    groupieArtistRef(l.ArtistID),
+		ArtistName:  l.ArtistName,
+		Slug:        l.Raw,
+		City:        l.City,
+		Country:     l.Country,
+		CountryCode: countryCode(l.Raw),
+		EventCount:  l.EventCount,
+	}
+}
+
+func toEventV2(e Event) eventV2 {
+	return eventV2{
+		Type:       "event",
+		ID:         groupieArtistRef(e.ArtistID) + ":" + e.Location + ":" + e.DateISO,
+		ArtistID:   groupieArtistRef(e.ArtistID),
+		ArtistName: e.ArtistName,
+		Location:   e.Location,
+		City:       e.City,
+		Country:    e.Country,
+		Date:       e.DateISO,
+		LocalDate:  e.LocalDate,
+		TimeZone:   e.TimeZone,
+		StartsAt:   e.StartsAt,
+	}
+}
+
+func mapSlice[T, U any](items []T, f func(T) U) []U {
+	out := make([]U, 0, len(items))
+	for _, item := range items {
+		out = append(out, f(item))
+	}
+	return out
+}
+
+func (a *App) v2Meta() v2Meta {
+	return v2Meta{APIVersion: "2", Version: a.cache.Version()}
+}
+
+// writeV2 sends one resource.
+func (a *App) writeV2(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, http.StatusOK, v2Envelope{Data: data, Meta: a.v2Meta(), Links: v2Links{Self: r.URL.RequestURI()}})
+}
+
+// writeV2List sends one page of a list; next is the cursor of the next page.
+func (a *App) writeV2List(w http.ResponseWriter, r *http.Request, data interface{}, count, total int, next string, facets map[string][]FacetValue) {
+	meta := a.v2Meta()
+	meta.Total = &total
+	meta.Count = &count
+	meta.Facets = facets
+	links := v2Links{Self: r.URL.RequestURI()}
+	if next != "" {
+		q := r.URL.Query()
+		q.Set("cursor", next)
+		links.Next = r.URL.Path + "?" + q.Encode()
+	}
+	writeJSON(w, http.StatusOK, v2Envelope{Data: data, Meta: meta, Links: links})
+}
+
+// v2Get checks the method and loads the cache; it reports whether to go on.
+func (a *App) v2Get(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return false
+	}
+	a.ensureCache(r.Context())
+	return true
+}
+
+func (a *App) handleV2Artists(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	started := time.Now()
+	q := r.URL.Query()
+	filters, err := parseArtistFilters(q)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	source := strings.ToLower(strings.TrimSpace(q.Get("source")))
+	switch source {
+	case "", sourceGroupie, sourceSpotify, "all":
+	default:
+		writeParamError(w, r, newParamError("source", codeInvalidSource))
+		return
+	}
+	if source == sourceSpotify && a.spotify == nil {
+		writeProblem(w, r, codeSpotifyDisabled)
+		return
+	}
+	// Spotify answers each search afresh, so a cursor into a list holding its
+	// results could skip or repeat artists.
+	withSpotify := source == sourceSpotify || source == "all"
+	if withSpotify && q.Get("cursor") != "" {
+		writeParamError(w, r, newParamError("cursor", codeCursorWithSpotify))
+		return
+	}
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	facets, err := parseFacets(q.Get("facets"), artistFacets)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+
+	filtered := filterArtists(a.cache.ArtistsWithMeta(), filters)
+	sortSpec := strings.TrimSpace(q.Get("sort"))
+	if filters.Fuzzy && sortSpec == "" {
+		sortSpec = "-matchScore"
+	}
+	if err := sortArtists(filtered, sortSpec); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
+		return
+	}
+	artists := make([]artistV2, 0, len(filtered))
+	if source != sourceSpotify {
+		artists = append(artists, mapSlice(filtered, toArtistV2)...)
+	}
+	if withSpotify && a.spotify != nil && filters.Name != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
+		defer cancel()
+		results, err := a.spotify.SearchArtists(ctx, filters.Name, 8)
+		if err != nil {
+			log.Printf("spotify search failed: %v", err)
+		}
+		a.linkSpotifyMatches(results)
+		for _, sa := range results {
+			image := pickBestImage(sa.Images)
+			if image == "" || strings.TrimSpace(sa.Name) == "" {
+				continue
+			}
+			artists = append(artists, spotifyArtistV2(sa.ID, sa.Name, image, sa.Genres, sa.Popularity))
+		}
+	}
+	a.recordSearch("artists", normalizeAnalyticsQuery(filters.Name, filters.Member), len(artists), started)
+
+	var facetCounts map[string][]FacetValue
+	if facets != nil {
+		facetCounts = computeArtistFacets(filtered, facets)
+	}
+	start, end, next := page.Bounds(len(artists))
+	if withSpotify {
+		next = ""
+	}
+	a.writeV2List(w, r, artists[start:end], end-start, len(artists), next, facetCounts)
+}
+
+func (a *App) handleV2ArtistByID(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, apiV2Prefix+"/artists/"), "/")
+	if key == "" {
+		a.handleV2Artists(w, r)
+		return
+	}
+	if id, ok := strings.CutPrefix(key, sourceSpotify+":"); ok {
+		a.handleV2SpotifyArtist(w, r, id)
+		return
+	}
+	key = strings.TrimPrefix(key, sourceGroupie+":")
+	art, ok := findArtist(a.cache.ArtistsWithMeta(), key)
+	if !ok {
+		writeProblem(w, r, codeArtistNotFound)
+		return
+	}
+	a.writeV2(w, r, toArtistV2(art))
+}
+
+func (a *App) handleV2SpotifyArtist(w http.ResponseWriter, r *http.Request, id string) {
+	if a.spotify == nil {
+		writeProblem(w, r, codeSpotifyDisabled)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
+	defer cancel()
+	artist, err := a.spotify.GetArtist(ctx, id)
+	if err != nil {
+		log.Printf("spotify artist lookup failed: %v", err)
+		writeProblem(w, r, mapSpotifyError(err))
+		return
+	}
+	a.linkSpotifyMatches([]SpotifyArtist{artist})
+	view := spotifyArtistV2(artist.ID, artist.Name, pickBestImage(artist.Images), artist.Genres, artist.Popularity)
+	followers := artist.Followers.Total
+	view.Followers = &followers
+	a.writeV2(w, r, view)
+}
+
+func (a *App) handleV2Members(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	nameFilter := normalizeMemberName(q.Get("name"))
+	minBands := 0
+	if minStr := strings.TrimSpace(q.Get("min_bands")); minStr != "" {
+		minBands, err = strconv.Atoi(minStr)
+		if err != nil || minBands < 0 {
+			writeParamError(w, r, newParamError("min_bands", codeInvalidBandCount))
+			return
+		}
+	}
+	members := make([]memberV2, 0)
+	for _, m := range a.cache.Members() {
+		if nameFilter != "" && !strings.Contains(m.NormalizedName, nameFilter) {
+			continue
+		}
+		if len(m.Bands) < minBands {
+			continue
+		}
+		members = append(members, toMemberV2(m))
+	}
+	start, end, next := page.Bounds(len(members))
+	a.writeV2List(w, r, members[start:end], end-start, len(members), next, nil)
+}
+
+func (a *App) handleV2MemberByID(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, apiV2Prefix+"/members/"), "/")
+	if id == "" {
+		a.handleV2Members(w, r)
+		return
+	}
+	for _, m := range a.cache.Members() {
+		if m.ID == id {
+			a.writeV2(w, r, toMemberV2(m))
+			return
+		}
+	}
+	writeProblem(w, r, codeMemberNotFound)
+}
+
+func (a *App) handleV2Locations(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	views := make([]viewLocation, 0)
+	for _, view := range buildLocationViews(a.cache.Snapshot()) {
+		if textContains(view.Country, q.Get("country")) && textContains(view.City, q.Get("city")) && textContains(view.ArtistName, q.Get("artist")) {
+			views = append(views, view)
+		}
+	}
+	if err := sortBy(views, strings.TrimSpace(q.Get("sort")), locationSortKeys); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
+		return
+	}
+	start, end, next := page.Bounds(len(views))
+	a.writeV2List(w, r, mapSlice(views[start:end], toLocationV2), end-start, len(views), next, nil)
+}
+
+func (a *App) handleV2Events(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	q := r.URL.Query()
+	filters, err := parseEventFilters(q, a.currentTime())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	facets, err := parseFacets(q.Get("facets"), eventFacets)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	filtered := filterEvents(a.cache.Events(), filters)
+	if err := sortBy(filtered, strings.TrimSpace(q.Get("sort")), eventSortKeys); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
+		return
+	}
+	var facetCounts map[string][]FacetValue
+	if facets != nil {
+		facetCounts = computeEventFacets(filtered, facets)
+	}
+	start, end, next := page.Bounds(len(filtered))
+	a.writeV2List(w, r, mapSlice(filtered[start:end], toEventV2), end-start, len(filtered), next, facetCounts)
+}
+
+// handleV2LocationByID serves /api/v2/locations/{id}, where id is the typed
+// location ID of the list, e.g. groupie:1:london-uk.
+func (a *App) handleV2LocationByID(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, apiV2Prefix+"/locations/"), "/")
+	if id == "" {
+		a.handleV2Locations(w, r)
+		return
+	}
+	for _, view := range buildLocationViews(a.cache.Snapshot()) {
+		if loc := toLocationV2(view); loc.ID == id {
+			a.writeV2(w, r, loc)
+			return
+		}
+	}
+	writeProblem(w, r, codeLocationNotFound)
+}
+
+// handleV2EventByID serves /api/v2/events/{id}, where id is the typed event
+// ID of the list, e.g. groupie:1:london-uk:1980-01-01.
+func (a *App) handleV2EventByID(w http.ResponseWriter, r *http.Request) {
+	if !a.v2Get(w, r) {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, apiV2Prefix+"/events/"), "/")
+	if id == "" {
+		a.handleV2Events(w, r)
+		return
+	}
+	for _, e := range a.cache.Events() {
+		if ev := toEventV2(e); ev.ID == id {
+			a.writeV2(w, r, ev)
+			return
+		}
+	}
+	writeProblem(w, r, codeEventNotFound)
+}
+
+// handleV2NotFound answers the rest of /api/v2. v2 covers artists, members,
+// locations and events; the other resources stay on v1, and asking for them
+// under /api/v2 names the v1 path instead of a bare 404.
+func (a *App) handleV2NotFound(w http.ResponseWriter, r *http.Request) {
+	v1 := "/api" + strings.TrimPrefix(r.URL.Path, apiV2Prefix)
+	for _, route := range a.apiRoutes() {
+		if strings.HasPrefix(route.Pattern, apiV2Prefix+"/") {
+			continue
+		}
+		if route.Pattern == v1 || (strings.HasSuffix(route.Pattern, "/") && strings.HasPrefix(v1, route.Pattern)) {
+			writeProblem(w, r, codeNotInV2, r.URL.Path, v1)
+			return
+		}
+	}
+	writeProblem(w, r, codeNotFound)
+}
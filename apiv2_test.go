@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type v2TestEnvelope struct {
+	Data  json.RawMessage `json:"data"`
+	Meta  v2Meta          `json:"meta"`
+	Links v2Links         `json:"links"`
+	// Problem fields, set on errors.
+	Status int     `json:"status"`
+	Code   string  `json:"code"`
+	Param  string  `json:"param"`
+	Error  *string `json:"error"`
+}
+
+func getV2(t *testing.T, app *App, target string) (int, v2TestEnvelope) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	var env v2TestEnvelope
+	if err := json.Unmarshal(rr.Body.Bytes(), &env); err != nil {
+		t.Fatalf("%s: decode %q: %v", target, rr.Body.String(), err)
+	}
+	return rr.Code, env
+}
+
+func TestV2ArtistsEnvelope(t *testing.T) {
+	app := graphQLTestApp()
+	code, env := getV2(t, app, "/api/v2/artists?sort=name&limit=1")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", code, env.Code)
+	}
+	var artists []artistV2
+	if err := json.Unmarshal(env.Data, &artists); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if len(artists) != 1 || artists[0].ID != "groupie:2" || artists[0].Type != "artist" || artists[0].ConcertCount != 1 {
+		t.Fatalf("unexpected artists %+v", artists)
+	}
+	if env.Meta.APIVersion != "2" || env.Meta.Total == nil || *env.Meta.Total != 2 || *env.Meta.Count != 1 {
+		t.Fatalf("unexpected meta %+v", env.Meta)
+	}
+	if !strings.HasPrefix(env.Links.Next, "/api/v2/artists?") || !strings.Contains(env.Links.Next, "cursor=") {
+		t.Fatalf("expected a next link, got %+v", env.Links)
+	}
+
+	code, env = getV2(t, app, env.Links.Next)
+	if code != http.StatusOK || env.Links.Next != "" {
+		t.Fatalf("expected the last page, got %d %+v", code, env.Links)
+	}
+}
+
+func TestV2ArtistsWithSpotifyAreOnePage(t *testing.T) {
+	app := graphQLTestApp()
+	code, env := getV2(t, app, "/api/v2/artists?source=all&sort=name&limit=1")
+	if code != http.StatusOK || *env.Meta.Count != 1 || env.Links.Next != "" {
+		t.Fatalf("expected a single page without next, got %d %+v %+v", code, env.Meta, env.Links)
+	}
+}
+
+func TestV2ArtistByTypedID(t *testing.T) {
+	app := graphQLTestApp()
+	for _, key := range []string{"groupie:1", "queen", "1"} {
+		code, env := getV2(t, app, "/api/v2/artists/"+key)
+		if code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", key, code)
+		}
+		var art artistV2
+		if err := json.Unmarshal(env.Data, &art); err != nil || art.Slug != "queen" || art.FirstAlbum != "1973-07-13" {
+			t.Fatalf("%s: unexpected artist %s (%v)", key, env.Data, err)
+		}
+	}
+}
+
+func TestV2ErrorsAreUniform(t *testing.T) {
+	app := graphQLTestApp()
+	cases := []struct {
+		target string
+		status int
+		code   string
+		param  string
+	}{
+		{"/api/v2/events?date_from=soon", http.StatusBadRequest, codeInvalidDate, "date_from"},
+		{"/api/v2/locations?sort=altitude", http.StatusBadRequest, codeInvalidSort, "sort"},
+		{"/api/v2/artists?source=all&cursor=abc", http.StatusBadRequest, codeCursorWithSpotify, "cursor"},
+		{"/api/v2/artists/nobody", http.StatusNotFound, codeArtistNotFound, ""},
+		{"/api/v2/artists/spotify:abc", http.StatusServiceUnavailable, codeSpotifyDisabled, ""},
+		{"/api/v2/members/nobody", http.StatusNotFound, codeMemberNotFound, ""},
+		{"/api/v2/events/groupie:2:berlin-germany:1990-10-11", http.StatusNotFound, codeEventNotFound, ""},
+		{"/api/v2/locations/groupie:1:berlin-germany", http.StatusNotFound, codeLocationNotFound, ""},
+		{"/api/v2/dates", http.StatusNotFound, codeNotInV2, ""},
+		{"/api/v2/countries/fr", http.StatusNotFound, codeNotInV2, ""},
+		{"/api/v2/nowhere", http.StatusNotFound, codeNotFound, ""},
+	}
+	for _, tc := range cases {
+		code, env := getV2(t, app, tc.target)
+		if code != tc.status || env.Status != tc.status || env.Code != tc.code || env.Param != tc.param {
+			t.Errorf("%s: unexpected %d %+v", tc.target, code, env)
+		}
+		if env.Error != nil {
+			t.Errorf("%s: v2 problems carry no legacy error field", tc.target)
+		}
+		if env.Data != nil {
+			t.Errorf("%s: error responses carry no data", tc.target)
+		}
+	}
+}
+
+func TestV2EventsAndMembers(t *testing.T) {
+	app := graphQLTestApp()
+	code, env := getV2(t, app, "/api/v2/events?country=germany")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	var events []eventV2
+	if err := json.Unmarshal(env.Data, &events); err != nil || len(events) != 1 {
+		t.Fatalf("unexpected events %s (%v)", env.Data, err)
+	}
+	if events[0].ID != "groupie:2:berlin-germany:1990-10-10" || events[0].ArtistID != "groupie:2" {
+		t.Fatalf("unexpected event IDs %+v", events[0])
+	}
+
+	code, env = getV2(t, app, "/api/v2/members?name=brian")
+	var members []memberV2
+	if err := json.Unmarshal(env.Data, &members); err != nil || code != http.StatusOK || len(members) != 1 {
+		t.Fatalf("unexpected members %d %s (%v)", code, env.Data, err)
+	}
+	if members[0].Bands[0].ArtistID != "groupie:1" {
+		t.Fatalf("expected typed band IDs, got %+v", members[0])
+	}
+}
+
+func TestV2LookupByTypedIDs(t *testing.T) {
+	app := graphQLTestApp()
+	code, env := getV2(t, app, "/api/v2/events/groupie:2:berlin-germany:1990-10-10")
+	var event eventV2
+	if err := json.Unmarshal(env.Data, &event); err != nil || code != http.StatusOK || event.City != "Berlin" {
+		t.Fatalf("unexpected event %d %s (%v)", code, env.Data, err)
+	}
+
+	code, env = getV2(t, app, "/api/v2/locations/groupie:1:paris-france")
+	var location locationV2
+	if err := json.Unmarshal(env.Data, &location); err != nil || code != http.StatusOK || location.ArtistName != "Queen" || location.EventCount != 1 {
+		t.Fatalf("unexpected location %d %s (%v)", code, env.Data, err)
+	}
+}
+
+func TestV2PointsMissingResourcesToV1(t *testing.T) {
+	rr, p := serveProblem(t, graphQLTestApp(), http.MethodGet, "/api/v2/relation", map[string]string{"Accept-Language": "en"})
+	if rr.Code != http.StatusNotFound || p.Detail != "/api/v2/relation does not exist in v2; use /api/relation" {
+		t.Fatalf("unexpected %d %+v", rr.Code, p)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	batchPath        = "/api/batch"
+	maxBatchRequests = 20
+	maxBatchBody     = 64 << 10
+	// maxBatchRedirects bounds the redirects followed per item, e.g. from a
+	// numeric artist ID to its slug.
+	maxBatchRedirects = 3
+)
+
+// batchHeaders are the sub-response headers copied into each result.
+var batchHeaders = []string{"Content-Type", "X-Total-Count", "X-Next-Cursor", "X-Missing-Ids"}
+
+type batchRequest struct {
+	Requests []batchItem `json:"requests"`
+}
+
+// batchItem is one read request: an /api path with its query string.
+type batchItem struct {
+	ID   string `json:"id,omitempty"`
+	Path string `json:"path"`
+}
+
+type batchResponse struct {
+	// Version identifies the data snapshot every item was served from.
+	Version string        `json:"version"`
+	Results []batchResult `json:"results"`
+}
+
+// batchResult is the outcome of one item. Body holds the JSON the path
+// returns on its own, a problem object on errors, or a string for non-JSON
+// formats.
+type batchResult struct {
+	ID      string            `json:"id,omitempty"`
+	Path    string            `json:"path"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// batchRecorder buffers a sub-response in memory.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header)}
+}
+
+func (rec *batchRecorder) Header() http.Header { return rec.header }
+
+func (rec *batchRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *batchRecorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
+
+// handleBatch serves POST /api/batch: several GET /api requests in one round
+// trip, all answered from the same data snapshot. Each item gets its own
+// status and body; the batch itself only fails on a malformed envelope.
+func (a *App) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		methodNotAllowed(w, r)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBatchBody+1))
+	if err != nil || len(body) > maxBatchBody {
+		writeProblem(w, r, codeBodyTooLarge, maxBatchBody)
+		return
+	}
+	var req batchRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeProblem(w, r, codeInvalidBody)
+		return
+	}
+	if len(req.Requests) == 0 || len(req.Requests) > maxBatchRequests {
+		writeParamError(w, r, newParamError("requests", codeInvalidBatchSize, maxBatchRequests))
+		return
+	}
+	a.ensureCache(r.Context())
+
+	// The snapshot app serves every item from the data as it is now; a
+	// refresh landing mid-batch does not reach it.
+	snap := *a
+	snap.cache = a.cache.pinned()
+	snap.api = nil
+	mux := http.NewServeMux()
+	for _, route := range snap.apiRoutes() {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, codeNotFound)
+	})
+
+	resp := batchResponse{Version: snap.cache.Version(), Results: make([]batchResult, len(req.Requests))}
+	for i, item := range req.Requests {
+		resp.Results[i] = runBatchItem(mux, r, item)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// runBatchItem serves one item through mux, following redirects within /api.
+func runBatchItem(mux http.Handler, parent *http.Request, item batchItem) (result batchResult) {
+	result = batchResult{ID: item.ID, Path: item.Path}
+	target := item.Path
+	for hop := 0; ; hop++ {
+		sub, ok := batchSubRequest(parent, target)
+		if !ok {
+			return problemResult(result, parent, codeInvalidBatchPath)
+		}
+		rec := newBatchRecorder()
+		if !serveBatchItem(mux, rec, sub) {
+			return problemResult(result, sub, codeInternal)
+		}
+		if location := rec.header.Get("Location"); isRedirect(rec.status) && location != "" && hop < maxBatchRedirects {
+			target = location
+			continue
+		}
+		result.Status = rec.status
+		if result.Status == 0 {
+			result.Status = http.StatusOK
+		}
+		result.Headers = make(map[string]string)
+		for _, name := range batchHeaders {
+			if v := rec.header.Get(name); v != "" {
+				result.Headers[name] = v
+			}
+		}
+		result.Body = batchBody(rec)
+		return result
+	}
+}
+
+// serveBatchItem runs one sub-request, reporting false if the handler panicked.
+func serveBatchItem(mux http.Handler, w http.ResponseWriter, r *http.Request) (ok bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("batch item %s panicked: %v", r.URL.RequestURI(), err)
+			ok = false
+		}
+	}()
+	mux.ServeHTTP(w, r)
+	return true
+}
+
+// batchSubRequest builds the GET request of an item. Only /api paths are
+// accepted, except the batch endpoint itself and the never-ending stream.
+func batchSubRequest(parent *http.Request, target string) (*http.Request, bool) {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/api/") {
+		return nil, false
+	}
+	if p := strings.TrimSuffix(u.Path, "/"); p == batchPath || p == streamPath {
+		return nil, false
+	}
+	sub, err := http.NewRequestWithContext(parent.Context(), http.MethodGet, u.RequestURI(), nil)
+	if err != nil {
+		return nil, false
+	}
+	sub.Host = parent.Host
+	sub.Header.Set("Accept", "application/json")
+	if lang := parent.Header.Get("Accept-Language"); lang != "" {
+		sub.Header.Set("Accept-Language", lang)
+	}
+	return sub, true
+}
+
+func isRedirect(status int) bool {
+	return status >= 300 && status < 400
+}
+
+// batchBody embeds JSON bodies as they are and other formats as a string.
+func batchBody(rec *batchRecorder) json.RawMessage {
+	body := bytes.TrimSpace(rec.body.Bytes())
+	mediaType, _, _ := mime.ParseMediaType(rec.header.Get("Content-Type"))
+	if (mediaType == "application/json" || mediaType == "application/problem+json") && json.Valid(body) {
+		return body
+	}
+	encoded, err := json.Marshal(string(body))
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return encoded
+}
+
+func problemResult(result batchResult, r *http.Request, code string) batchResult {
+	p := newProblem(r, code)
+	body, err := json.Marshal(p)
+	if err != nil {
+		body = []byte("null")
+	}
+	result.Status = p.Status
+	result.Headers = map[string]string{"Content-Type": "application/problem+json; charset=utf-8"}
+	result.Body = body
+	return result
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestArtistsByIDs(t *testing.T) {
+	app := graphQLTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?ids=2,99,1,2", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	var artists []ArtistWithMeta
+	if err := json.Unmarshal(rr.Body.Bytes(), &artists); err != nil {
+		t.Fatalf("decode %s: %v", rr.Body.String(), err)
+	}
+	if len(artists) != 2 || artists[0].ID != 2 || artists[1].ID != 1 {
+		t.Fatalf("expected artists 2 then 1, got %+v", artists)
+	}
+	if got := rr.Header().Get("X-Missing-Ids"); got != "99" {
+		t.Fatalf("expected X-Missing-Ids 99, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/artists?ids=2,1&sort=name", nil)
+	rr = httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	artists = nil
+	json.Unmarshal(rr.Body.Bytes(), &artists)
+	if len(artists) != 2 || artists[0].Name != "Pink Floyd" || rr.Header().Get("X-Missing-Ids") != "" {
+		t.Fatalf("sort should win over ids order, got %+v", artists)
+	}
+
+	for _, query := range []string{"ids=1,x", "ids=0", "ids=" + strings.Repeat("1,", maxArtistIDs) + "1"} {
+		rr, p := serveProblem(t, app, http.MethodGet, "/api/artists?"+query, nil)
+		if rr.Code != http.StatusBadRequest || p.Code != codeInvalidIDs || p.Param != "ids" {
+			t.Errorf("%s: unexpected %d %+v", query, rr.Code, p)
+		}
+	}
+}
+
+func postBatch(t *testing.T, app *App, body string) (*httptest.ResponseRecorder, batchResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, batchPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	var resp batchResponse
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", rr.Body.String(), err)
+		}
+	}
+	return rr, resp
+}
+
+func TestBatchPerItemResults(t *testing.T) {
+	app := graphQLTestApp()
+	rr, resp := postBatch(t, app, `{"requests": [
+		{"id": "fav", "path": "/api/artists/1"},
+		{"id": "gigs", "path": "/api/events?country=germany&limit=1"},
+		{"id": "gone", "path": "/api/artists/nobody"},
+		{"id": "bad", "path": "/api/artists?year=abc"},
+		{"id": "page", "path": "/"},
+		{"id": "loop", "path": "/api/batch"},
+		{"id": "lost", "path": "/api/nowhere"}
+	]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if resp.Version != app.cache.Version() || len(resp.Results) != 7 {
+		t.Fatalf("unexpected batch %+v", resp)
+	}
+	want := []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{"fav", http.StatusOK, ""},
+		{"gigs", http.StatusOK, ""},
+		{"gone", http.StatusNotFound, codeArtistNotFound},
+		{"bad", http.StatusBadRequest, codeInvalidYear},
+		{"page", http.StatusBadRequest, codeInvalidBatchPath},
+		{"loop", http.StatusBadRequest, codeInvalidBatchPath},
+		{"lost", http.StatusNotFound, codeNotFound},
+	}
+	for i, w := range want {
+		res := resp.Results[i]
+		if res.ID != w.id || res.Status != w.status {
+			t.Errorf("%s: unexpected result %+v", w.id, res)
+			continue
+		}
+		if w.code != "" {
+			var p problem
+			if err := json.Unmarshal(res.Body, &p); err != nil || p.Code != w.code {
+				t.Errorf("%s: expected %s, got %s", w.id, w.code, res.Body)
+			}
+		}
+	}
+
+	var queen ArtistWithMeta
+	if err := json.Unmarshal(resp.Results[0].Body, &queen); err != nil || queen.Name != "Queen" {
+		t.Fatalf("redirect not followed: %s", resp.Results[0].Body)
+	}
+	var events []Event
+	if err := json.Unmarshal(resp.Results[1].Body, &events); err != nil || len(events) != 1 || events[0].City == "" {
+		t.Fatalf("unexpected events %s", resp.Results[1].Body)
+	}
+	if resp.Results[1].Headers["X-Total-Count"] != "1" {
+		t.Fatalf("pagination headers missing: %+v", resp.Results[1].Headers)
+	}
+}
+
+func TestBatchRejectsMalformedEnvelope(t *testing.T) {
+	app := graphQLTestApp()
+	tooMany := make([]string, maxBatchRequests+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf(`{"path": "/api/artists/%d"}`, i+1)
+	}
+	cases := map[string]struct {
+		body   string
+		status int
+	}{
+		"empty":    {`{"requests": []}`, http.StatusBadRequest},
+		"not json": {`requests`, http.StatusBadRequest},
+		"too many": {`{"requests": [` + strings.Join(tooMany, ",") + `]}`, http.StatusBadRequest},
+		"too big":  {`{"requests": [], "pad": "` + strings.Repeat("x", maxBatchBody) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for name, tc := range cases {
+		rr, _ := postBatch(t, app, tc.body)
+		if rr.Code != tc.status || !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/problem+json") {
+			t.Errorf("%s: expected %d problem, got %d %s", name, tc.status, rr.Code, rr.Header().Get("Content-Type"))
+		}
+	}
+
+	rr, _ := serveProblem(t, app, http.MethodGet, batchPath, nil)
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Allow") != http.MethodPost {
+		t.Fatalf("expected 405 allowing POST, got %d %q", rr.Code, rr.Header().Get("Allow"))
+	}
+}
+
+func TestPinnedCacheIgnoresRefreshes(t *testing.T) {
+	app := graphQLTestApp()
+	pinned := app.cache.pinned()
+	version := pinned.Version()
+	app.cache.Set(DataBundle{Artists: []Artist{{ID: 7, Name: "Newcomer"}}})
+	if pinned.Version() != version || app.cache.Version() == version {
+		t.Fatalf("pinned version moved with the cache")
+	}
+	if artists := pinned.ArtistsWithMeta(); len(artists) != 2 || artists[0].Name != "Queen" {
+		t.Fatalf("pinned data changed: %+v", artists)
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"sync"
 	"time"
 )
@@ -10,18 +13,135 @@ type Cache struct {
 	mu        sync.RWMutex
 	data      DataBundle
 	fetchedAt time.Time
+	version   string
+	index     *searchIndex
+	suggest   *suggestIndex
+	// announced lists concerts that appeared on refreshes, oldest first.
+	announced []Announcement
+}
+
+// maxAnnouncements bounds the history of announced concerts.
+const maxAnnouncements = 500
+
+// Announcement is a concert that was not in the dataset before a refresh.
+type Announcement struct {
+	Event       Event
+	AnnouncedAt time.Time
 }
 
 func newCache() *Cache {
 	return &Cache{}
 }
 
-// Set replaces the cached data with a fresh copy.
-func (c *Cache) Set(bundle DataBundle) {
+// dataChange describes what a Set changed. Added and Removed stay empty on
+// the first load, which is the baseline.
+type dataChange struct {
+	Version   string
+	Changed   bool
+	FetchedAt time.Time
+	Added     []Event
+	Removed   []Event
+}
+
+// Set replaces the cached data with a fresh copy and rebuilds the search indexes.
+func (c *Cache) Set(bundle DataBundle) dataChange {
+	index := buildSearchIndex(bundle)
+	suggest := buildSuggestIndex(bundle)
+	version := bundleVersion(bundle)
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	now := time.Now()
+	change := dataChange{Version: version, Changed: c.version != version, FetchedAt: now}
+	// The first load is the baseline: nothing is new yet.
+	if c.version != "" && change.Changed {
+		change.Added = newEvents(c.data, bundle)
+		change.Removed = newEvents(bundle, c.data)
+		for _, ev := range change.Added {
+			c.announced = append(c.announced, Announcement{Event: ev, AnnouncedAt: now})
+		}
+		if extra := len(c.announced) - maxAnnouncements; extra > 0 {
+			c.announced = append([]Announcement(nil), c.announced[extra:]...)
+		}
+	}
 	c.data = bundle
-	c.fetchedAt = time.Now()
+	c.fetchedAt = now
+	c.version = version
+	c.index = index
+	c.suggest = suggest
+	return change
+}
+
+// pinned returns a read-only copy of the current state. Later refreshes of c
+// do not reach it, so requests served from it see one consistent snapshot.
+func (c *Cache) pinned() *Cache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &Cache{
+		data:      c.data,
+		fetchedAt: c.fetchedAt,
+		version:   c.version,
+		index:     c.index,
+		suggest:   c.suggest,
+		announced: c.announced,
+	}
+}
+
+// Version identifies the cached snapshot. It is a hash of the data, so it only
+// changes when a refresh actually brings new content.
+func (c *Cache) Version() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.version
+}
+
+func bundleVersion(bundle DataBundle) string {
+	h := fnv.New64a()
+	// Map keys are sorted by encoding/json, so equal data always hashes the same.
+	if err := json.NewEncoder(h).Encode(bundle); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// newEvents returns the concerts of next that are not in prev, matched by
+// artist, location and date.
+func newEvents(prev, next DataBundle) []Event {
+	seen := make(map[string]bool)
+	for _, ev := range buildEvents(prev.Artists, prev.Relations) {
+		seen[eventUID(ev)] = true
+	}
+	var out []Event
+	for _, ev := range buildEvents(next.Artists, next.Relations) {
+		if !seen[eventUID(ev)] {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// Announcements returns the concerts announced by refreshes, newest first.
+func (c *Cache) Announcements() []Announcement {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Announcement, len(c.announced))
+	for i, a := range c.announced {
+		out[len(out)-1-i] = a
+	}
+	return out
+}
+
+// FetchedAt returns when the cached data was last replaced.
+func (c *Cache) FetchedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fetchedAt
+}
+
+// SearchIndex returns the index built on the last refresh. It is never mutated.
+func (c *Cache) SearchIndex() *searchIndex {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.index
 }
 
 // Snapshot returns a copy of the cached data to prevent callers from mutating it.
@@ -36,6 +156,13 @@ func (c *Cache) Snapshot() DataBundle {
 	}
 }
 
+// SuggestIndex returns the autocomplete table built on the last refresh.
+func (c *Cache) SuggestIndex() *suggestIndex {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.suggest
+}
+
 func (c *Cache) ArtistsWithMeta() []ArtistWithMeta {
 	return mergeArtists(c.Snapshot())
 }
@@ -45,9 +172,16 @@ func (c *Cache) Events() []Event {
 	return buildEvents(snap.Artists, snap.Relations)
 }
 
+func (c *Cache) Members() []Member {
+	return buildMembers(c.Snapshot().Artists)
+}
+
 func cloneArtists(src []Artist) []Artist {
 	out := make([]Artist, len(src))
 	copy(out, src)
+	for i := range out {
+		out[i].Members = append([]string(nil), src[i].Members...)
+	}
 	return out
 }
 
@@ -50,11 +50,32 @@ type DataBundle struct {
 }
 
 // ArtistWithMeta enriches an Artist with resolved locations, dates and relations.
+// FirstAlbumDate is parsed once from the raw FirstAlbum string; it stays zero when
+// the upstream value cannot be parsed.
 type ArtistWithMeta struct {
 	Artist
-	LocationList   []string            `json:"locations,omitempty"`
-	DateList       []string            `json:"dates,omitempty"`
-	DatesLocations map[string][]string `json:"datesLocations,omitempty"`
+	Slug              string              `json:"slug"`
+	LocationList      []string            `json:"locations,omitempty"`
+	DateList          []string            `json:"dates,omitempty"`
+	DatesLocations    map[string][]string `json:"datesLocations,omitempty"`
+	FirstAlbumDate    time.Time           `json:"-"`
+	FirstAlbumISO     string              `json:"firstAlbumDate,omitempty"`
+	FirstAlbumYear    int                 `json:"firstAlbumYear,omitempty"`
+	YearsToFirstAlbum *int                `json:"yearsToFirstAlbum,omitempty"`
+	MatchScore        *float64            `json:"matchScore,omitempty"`
+}
+
+// score returns the fuzzy match score, or zero when the artist was not scored.
+func (a ArtistWithMeta) score() float64 {
+	if a.MatchScore == nil {
+		return 0
+	}
+	return *a.MatchScore
+}
+
+// HasFirstAlbum reports whether the first album date could be parsed.
+func (a ArtistWithMeta) HasFirstAlbum() bool {
+	return !a.FirstAlbumDate.IsZero()
 }
 
 // LocationName contains a human readable location for a slug.
@@ -65,13 +86,52 @@ type LocationName struct {
 }
 
 // Event represents a single concert event with human readable details.
+// Date is the concert day at UTC midnight and is kept for ordering; LocalDate and
+// StartsAt describe the same day in the venue's time zone.
 type Event struct {
 	ArtistID   int       `json:"artistId"`
 	ArtistName string    `json:"artistName"`
 	City       string    `json:"city"`
 	Country    string    `json:"country"`
+	Location   string    `json:"location"`
 	Date       time.Time `json:"-"`
 	DateISO    string    `json:"date"`
+	TimeZone   string    `json:"timeZone"`
+	LocalDate  string    `json:"localDate"`
+	StartsAt   time.Time `json:"startsAt"`
+}
+
+// EndsAt returns the UTC instant at which the venue's local concert day ends.
+func (e Event) EndsAt() time.Time {
+	loc := loadZone(e.TimeZone)
+	local := e.StartsAt.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc).UTC()
+}
+
+// Event windows accepted by the events endpoint.
+const (
+	windowToday    = "today"
+	windowUpcoming = "upcoming"
+	windowPast     = "past"
+)
+
+// inWindow reports whether the event falls in the given window as seen from userLoc at now.
+// An event covers the whole local day at its venue: it is upcoming until that day is over,
+// past afterwards, and "today" when the venue day overlaps the user's current day.
+func (e Event) inWindow(window string, now time.Time, userLoc *time.Location) bool {
+	start, end := e.StartsAt, e.EndsAt()
+	switch window {
+	case windowUpcoming:
+		return end.After(now)
+	case windowPast:
+		return !end.After(now)
+	case windowToday:
+		local := now.In(userLoc)
+		dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, userLoc)
+		dayEnd := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, userLoc)
+		return start.Before(dayEnd) && end.After(dayStart)
+	}
+	return true
 }
 
 // parseAPIDate handles the different date formats returned by the upstream API.
@@ -143,16 +203,34 @@ func mergeArtists(bundle DataBundle) []ArtistWithMeta {
 
 	enriched := make([]ArtistWithMeta, 0, len(bundle.Artists))
 	for _, art := range bundle.Artists {
-		enriched = append(enriched, ArtistWithMeta{
+		meta := ArtistWithMeta{
 			Artist:         art,
 			LocationList:   locMap[art.ID],
 			DateList:       dateMap[art.ID],
 			DatesLocations: relMap[art.ID],
-		})
+		}
+		resolveFirstAlbum(&meta)
+		enriched = append(enriched, meta)
 	}
+	assignSlugs(enriched)
 	return enriched
 }
 
+// resolveFirstAlbum parses the raw first album string and fills the typed and derived fields.
+func resolveFirstAlbum(meta *ArtistWithMeta) {
+	ts, err := parseAPIDate(meta.FirstAlbum)
+	if err != nil {
+		return
+	}
+	meta.FirstAlbumDate = ts
+	meta.FirstAlbumISO = ts.Format("2006-01-02")
+	meta.FirstAlbumYear = ts.Year()
+	if meta.CreationDate > 0 {
+		gap := ts.Year() - meta.CreationDate
+		meta.YearsToFirstAlbum = &gap
+	}
+}
+
 // buildEvents flattens relations into a chronological list of events with artist names.
 func buildEvents(artists []Artist, relations []Relation) []Event {
 	nameByID := make(map[int]string, len(artists))
@@ -164,6 +242,8 @@ func buildEvents(artists []Artist, relations []Relation) []Event {
 	for _, rel := range relations {
 		for slug, dates := range rel.DatesLocations {
 			loc := splitLocationSlug(slug)
+			tzName := locationTimeZone(slug)
+			zone := loadZone(tzName)
 			for _, d := range dates {
 				ts, err := parseAPIDate(d)
 				if err != nil {
@@ -174,14 +254,25 @@ func buildEvents(artists []Artist, relations []Relation) []Event {
 					ArtistName: nameByID[rel.ID],
 					City:       loc.City,
 					Country:    loc.Country,
+					Location:   slug,
 					Date:       ts,
+					TimeZone:   tzName,
+					LocalDate:  ts.Format("2006-01-02"),
+					StartsAt:   time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, zone).UTC(),
 				})
 			}
 		}
 	}
 
+	// Ties are broken on artist and location so the order is deterministic.
 	sort.Slice(events, func(i, j int) bool {
-		return events[i].Date.Before(events[j].Date)
+		if !events[i].Date.Equal(events[j].Date) {
+			return events[i].Date.Before(events[j].Date)
+		}
+		if events[i].ArtistID != events[j].ArtistID {
+			return events[i].ArtistID < events[j].ArtistID
+		}
+		return events[i].Location < events[j].Location
 	})
 
 	for i := range events {
@@ -66,3 +66,62 @@ func TestBuildEvents(t *testing.T) {
 		}
 	}
 }
+
+func TestMergeArtistsFirstAlbum(t *testing.T) {
+	merged := mergeArtists(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Queen", CreationDate: 1970, FirstAlbum: "14-12-1973"},
+			{ID: 2, Name: "Broken", CreationDate: 2000, FirstAlbum: "n/a"},
+		},
+	})
+	if merged[0].FirstAlbumISO != "1973-12-14" || merged[0].FirstAlbumYear != 1973 {
+		t.Fatalf("unexpected first album %+v", merged[0])
+	}
+	if merged[0].YearsToFirstAlbum == nil || *merged[0].YearsToFirstAlbum != 3 {
+		t.Fatalf("expected 3 years to first album, got %v", merged[0].YearsToFirstAlbum)
+	}
+	if merged[1].HasFirstAlbum() || merged[1].YearsToFirstAlbum != nil {
+		t.Fatalf("unparsable album should stay empty: %+v", merged[1])
+	}
+}
+
+func TestEventWindowsAcrossZones(t *testing.T) {
+	events := buildEvents([]Artist{{ID: 1, Name: "Tz"}}, []Relation{
+		{ID: 1, DatesLocations: map[string][]string{"tokyo-japan": {"10-03-2024"}}},
+	})
+	ev := events[0]
+	if ev.TimeZone != "Asia/Tokyo" || ev.LocalDate != "2024-03-10" {
+		t.Fatalf("unexpected zone data %+v", ev)
+	}
+	if got := ev.StartsAt.Format(time.RFC3339); got != "2024-03-09T15:00:00Z" {
+		t.Fatalf("unexpected UTC start %s", got)
+	}
+
+	// 16:00 UTC on March 10th is already March 11th in Tokyo: the concert is over.
+	now := time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC)
+	if !ev.inWindow(windowPast, now, time.UTC) || ev.inWindow(windowUpcoming, now, time.UTC) {
+		t.Fatalf("expected Tokyo concert to be past at %s", now)
+	}
+	// For a user in Los Angeles it is still March 9th, which overlaps the Tokyo day.
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	if !ev.inWindow(windowToday, time.Date(2024, 3, 9, 20, 0, 0, 0, time.UTC), la) {
+		t.Fatalf("expected concert to be today for a Los Angeles user")
+	}
+}
+
+func TestBuildMembersCrossBand(t *testing.T) {
+	members := buildMembers([]Artist{
+		{ID: 1, Name: "Alpha", Members: []string{"John  Doe", "Jane Roe"}},
+		{ID: 2, Name: "Beta", Members: []string{"john doe"}},
+	})
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, got %+v", members)
+	}
+	john := members[1]
+	if john.ID != "john-doe" || len(john.Bands) != 2 {
+		t.Fatalf("expected john-doe in two bands, got %+v", john)
+	}
+	if john.Bands[0].ArtistName != "Alpha" || john.Bands[1].ArtistName != "Beta" {
+		t.Fatalf("unexpected bands %+v", john.Bands)
+	}
+}
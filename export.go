@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Response formats of the list endpoints.
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// exportMediaTypes maps Accept media types to a response format.
+var exportMediaTypes = map[string]string{
+	"application/json":     formatJSON,
+	"application/*":        formatJSON,
+	"*/*":                  formatJSON,
+	"text/csv":             formatCSV,
+	"application/x-ndjson": formatNDJSON,
+	"application/ndjson":   formatNDJSON,
+}
+
+// listSeparator joins list values inside one CSV cell. Inside values it is
+// escaped as \| (and a backslash as \\), so a cell always splits back into
+// the original list.
+const listSeparator = "|"
+
+var listEscaper = strings.NewReplacer(`\`, `\\`, listSeparator, `\`+listSeparator)
+
+// negotiateFormat picks the response format of a list endpoint. An explicit
+// format parameter wins over the Accept header; anything else gets JSON.
+func negotiateFormat(w http.ResponseWriter, r *http.Request) (string, error) {
+	w.Header().Add("Vary", "Accept")
+	if v := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); v != "" {
+		switch v {
+		case formatJSON, formatCSV, formatNDJSON:
+			return v, nil
+		}
+		return "", newParamError("format", codeInvalidFormat)
+	}
+	best, bestQ := formatJSON, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		format, ok := exportMediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best, nil
+}
+
+// checkExportOptions rejects parameters that only make sense in JSON.
+func checkExportOptions(format string, facets []string, sel fieldSelection) error {
+	if format == formatJSON {
+		return nil
+	}
+	if facets != nil {
+		return newParamError("facets", codeFacetsJSONOnly)
+	}
+	if format == formatCSV && len(sel.Include) > 0 {
+		return newParamError("include", codeIncludeJSONOnly)
+	}
+	return nil
+}
+
+// csvColumn is one column of a CSV export. Name matches the JSON field name
+// so sparse fieldsets select columns the same way they select fields.
+type csvColumn[T any] struct {
+	Name  string
+	Value func(T) string
+}
+
+// selectColumns keeps the columns named in a sparse fieldset.
+func selectColumns[T any](columns []csvColumn[T], sel fieldSelection) []csvColumn[T] {
+	if sel.Fields == nil {
+		return columns
+	}
+	out := make([]csvColumn[T], 0, len(columns))
+	for _, col := range columns {
+		if sel.Fields[col.Name] {
+			out = append(out, col)
+		}
+	}
+	return out
+}
+
+func attachment(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeCSV streams items as CSV with a header row.
+func writeCSV[T any](w http.ResponseWriter, name string, items []T, columns []csvColumn[T]) {
+	attachment(w, "text/csv; charset=utf-8; header=present", name+".csv")
+	cw := csv.NewWriter(w)
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = col.Name
+	}
+	if err := cw.Write(row); err != nil {
+		log.Printf("write csv: %v", err)
+		return
+	}
+	for _, item := range items {
+		for i, col := range columns {
+			row[i] = csvCell(col.Value(item))
+		}
+		if err := cw.Write(row); err != nil {
+			log.Printf("write csv: %v", err)
+			return
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("write csv: %v", err)
+	}
+}
+
+// writeNDJSON streams a slice as newline-delimited JSON, one item per line.
+func writeNDJSON(w http.ResponseWriter, name string, items interface{}) {
+	attachment(w, "application/x-ndjson", name+".ndjson")
+	list := reflect.ValueOf(items)
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := 0; i < list.Len(); i++ {
+		if err := enc.Encode(list.Index(i).Interface()); err != nil {
+			log.Printf("write ndjson: %v", err)
+			return
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		log.Printf("write ndjson: %v", err)
+	}
+}
+
+// writeResults sends a JSON list, or NDJSON when negotiated. Callers handle
+// CSV themselves since it needs typed columns.
+func writeResults(w http.ResponseWriter, format, name string, results interface{}) {
+	if format == formatNDJSON {
+		writeNDJSON(w, name, results)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// writeList sends a page of results in the negotiated format.
+func writeList[T any](w http.ResponseWriter, format, name string, items []T, columns []csvColumn[T]) {
+	if format == formatCSV {
+		writeCSV(w, name, items, columns)
+		return
+	}
+	writeResults(w, format, name, items)
+}
+
+func csvInt(v int) string {
+	return strconv.Itoa(v)
+}
+
+// csvOptionalInt leaves zero values empty.
+func csvOptionalInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+func csvScore(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// csvCell keeps spreadsheets from evaluating a value as a formula by
+// prefixing the characters that start one with a quote.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func csvList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = listEscaper.Replace(v)
+	}
+	return strings.Join(escaped, listSeparator)
+}
+
+// csvDatesLocations flattens a relation to one location:date pair per
+// concert, ordered by location and then by upstream date order.
+func csvDatesLocations(m map[string][]string) string {
+	locations := make([]string, 0, len(m))
+	for loc := range m {
+		locations = append(locations, loc)
+	}
+	sort.Strings(locations)
+	pairs := make([]string, 0, len(m)*2)
+	for _, loc := range locations {
+		for _, date := range m[loc] {
+			pairs = append(pairs, loc+":"+date)
+		}
+	}
+	return csvList(pairs)
+}
+
+var artistColumns = []csvColumn[ArtistWithMeta]{
+	{"id", func(a ArtistWithMeta) string { return csvInt(a.ID) }},
+	{"slug", func(a ArtistWithMeta) string { return a.Slug }},
+	{"name", func(a ArtistWithMeta) string { return a.Name }},
+	{"image", func(a ArtistWithMeta) string { return a.Image }},
+	{"members", func(a ArtistWithMeta) string { return csvList(a.Members) }},
+	{"creationDate", func(a ArtistWithMeta) string { return csvOptionalInt(a.CreationDate) }},
+	{"firstAlbum", func(a ArtistWithMeta) string { return a.FirstAlbum }},
+	{"firstAlbumDate", func(a ArtistWithMeta) string { return a.FirstAlbumISO }},
+	{"firstAlbumYear", func(a ArtistWithMeta) string { return csvOptionalInt(a.FirstAlbumYear) }},
+	{"yearsToFirstAlbum", func(a ArtistWithMeta) string {
+		if a.YearsToFirstAlbum == nil {
+			return ""
+		}
+		return csvInt(*a.YearsToFirstAlbum)
+	}},
+	{"locations", func(a ArtistWithMeta) string { return csvList(a.LocationList) }},
+	{"dates", func(a ArtistWithMeta) string { return csvList(a.DateList) }},
+	{"datesLocations", func(a ArtistWithMeta) string { return csvDatesLocations(a.DatesLocations) }},
+	{"matchScore", func(a ArtistWithMeta) string { return csvScore(a.MatchScore) }},
+}
+
+var unifiedArtistColumns = []csvColumn[UnifiedArtist]{
+	{"id", func(a UnifiedArtist) string { return a.ID }},
+	{"slug", func(a UnifiedArtist) string { return a.Slug }},
+	{"name", func(a UnifiedArtist) string { return a.Name }},
+	{"image_url", func(a UnifiedArtist) string { return a.ImageURL }},
+	{"source", func(a UnifiedArtist) string { return a.Source }},
+	{"creationDate", func(a UnifiedArtist) string { return csvOptionalInt(a.CreationDate) }},
+	{"firstAlbum", func(a UnifiedArtist) string { return a.FirstAlbum }},
+	{"firstAlbumYear", func(a UnifiedArtist) string { return csvOptionalInt(a.FirstAlbumYear) }},
+	{"members", func(a UnifiedArtist) string { return csvList(a.Members) }},
+	{"genres", func(a UnifiedArtist) string { return csvList(a.Genres) }},
+	{"popularity", func(a UnifiedArtist) string { return csvOptionalInt(a.Popularity) }},
+	{"matchScore", func(a UnifiedArtist) string { return csvScore(a.MatchScore) }},
+}
+
+var locationColumns = []csvColumn[viewLocation]{
+	{"artistId", func(l viewLocation) string { return csvInt(l.ArtistID) }},
+	{"artistName", func(l viewLocation) string { return l.ArtistName }},
+	{"city", func(l viewLocation) string { return l.City }},
+	{"country", func(l viewLocation) string { return l.Country }},
+	{"raw", func(l viewLocation) string { return l.Raw }},
+	{"eventCount", func(l viewLocation) string { return csvInt(l.EventCount) }},
+}
+
+var eventColumns = []csvColumn[Event]{
+	{"artistId", func(e Event) string { return csvInt(e.ArtistID) }},
+	{"artistName", func(e Event) string { return e.ArtistName }},
+	{"city", func(e Event) string { return e.City }},
+	{"country", func(e Event) string { return e.Country }},
+	{"location", func(e Event) string { return e.Location }},
+	{"date", func(e Event) string { return e.DateISO }},
+	{"timeZone", func(e Event) string { return e.TimeZone }},
+	{"localDate", func(e Event) string { return e.LocalDate }},
+	{"startsAt", func(e Event) string { return e.StartsAt.UTC().Format("2006-01-02T15:04:05Z") }},
+}
+
+var datesColumns = []csvColumn[DatesIndex]{
+	{"id", func(d DatesIndex) string { return csvInt(d.ID) }},
+	{"dates", func(d DatesIndex) string { return csvList(d.Dates) }},
+}
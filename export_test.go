@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		target, accept, want string
+	}{
+		{"/api/events", "", formatJSON},
+		{"/api/events", "text/html, */*;q=0.8", formatJSON},
+		{"/api/events", "text/csv", formatCSV},
+		{"/api/events", "application/json;q=0.5, application/x-ndjson", formatNDJSON},
+		{"/api/events", "text/csv;q=0.2, application/json;q=0.9", formatJSON},
+		{"/api/events?format=csv", "application/json", formatCSV},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		req.Header.Set("Accept", tc.accept)
+		got, err := negotiateFormat(httptest.NewRecorder(), req)
+		if err != nil || got != tc.want {
+			t.Errorf("%s with Accept %q: expected %s, got %s (%v)", tc.target, tc.accept, tc.want, got, err)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/events?format=xml", nil)
+	if _, err := negotiateFormat(httptest.NewRecorder(), req); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestArtistsCSVFlattensNestedFields(t *testing.T) {
+	app := fieldsetTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?format=csv&fields=name,members,datesLocations", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := [][]string{
+		{"id", "slug", "name", "members", "datesLocations"},
+		{"1", "queen", "Queen", "Freddie Mercury", "london-uk:01-01-1980|london-uk:02-01-1980"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %v", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Fatalf("row %d: expected %v, got %v", i, want[i], rows[i])
+		}
+	}
+}
+
+func TestCSVEscapesListsAndFormulas(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{Artists: []Artist{
+		{ID: 1, Name: "@Band", Members: []string{"=SUM(A1)", "Tom|Jerry", `C:\x`}},
+	}})
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, httptest.NewRequest(http.MethodGet, "/api/artists?format=csv&fields=name,members", nil))
+	rows, err := csv.NewReader(rr.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	want := []string{"1", "band", "'@Band", `'=SUM(A1)|Tom\|Jerry|C:\\x`}
+	if len(rows) != 2 || strings.Join(rows[1], ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %q, got %q", want, rows)
+	}
+}
+
+func TestEventsNDJSON(t *testing.T) {
+	app := fieldsetTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+	app.handleAPIEvents(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per event, got %q", rr.Body.String())
+	}
+	var ev Event
+	if err := json.Unmarshal([]byte(lines[0]), &ev); err != nil || ev.DateISO != "1980-01-01" {
+		t.Fatalf("unexpected first line %q (%v)", lines[0], err)
+	}
+	if rr.Header().Get("X-Total-Count") != "2" {
+		t.Fatalf("expected pagination headers, got %v", rr.Header())
+	}
+}
+
+func TestExportRejectsJSONOnlyOptions(t *testing.T) {
+	app := fieldsetTestApp()
+	for _, target := range []string{"/api/events?format=csv&facets=all", "/api/artists?format=csv&include=events"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		if strings.HasPrefix(target, "/api/events") {
+			app.handleAPIEvents(rr, req)
+		} else {
+			app.handleAPIArtists(rr, req)
+		}
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rr.Code)
+		}
+	}
+}
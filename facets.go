@@ -0,0 +1,160 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Facet names accepted by the facets parameter.
+const (
+	facetCreationDecade   = "creationDecade"
+	facetMemberCount      = "memberCount"
+	facetFirstAlbumDecade = "firstAlbumDecade"
+	facetConcertCountry   = "concertCountry"
+	facetConcertYear      = "concertYear"
+)
+
+// artistFacets and eventFacets list the facets each endpoint can compute, in output order.
+var (
+	artistFacets = []string{facetCreationDecade, facetMemberCount, facetFirstAlbumDecade, facetConcertCountry, facetConcertYear}
+	eventFacets  = []string{facetConcertCountry, facetConcertYear}
+)
+
+// FacetValue is one bucket of a facet with the number of results it contains.
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// facetedResponse wraps a filtered result list with its facet counts.
+type facetedResponse struct {
+	Results interface{}             `json:"results"`
+	Facets  map[string][]FacetValue `json:"facets"`
+}
+
+// parseFacets validates the facets parameter against the facets an endpoint supports.
+// "all" (or "1"/"true") selects every supported facet; an empty value selects none.
+// Errors are *paramError.
+func parseFacets(value string, supported []string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if value == "all" || parseBool(value) {
+		return supported, nil
+	}
+	known := make(map[string]bool, len(supported))
+	for _, f := range supported {
+		known[f] = true
+	}
+	var out []string
+	for _, f := range strings.Split(value, ",") {
+		f = strings.TrimSpace(f)
+		if !known[f] {
+			return nil, newParamError("facets", codeInvalidFacet, f)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func decade(year int) string {
+	return strconv.Itoa(year/10*10) + "s"
+}
+
+// facetCounter counts how many results fall in each bucket. A result is counted
+// at most once per bucket even if it matches the bucket several times.
+type facetCounter map[string]int
+
+func (c facetCounter) addOnce(values []string) {
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		c[v]++
+	}
+}
+
+// sorted returns buckets in natural order; numeric buckets sort numerically.
+func (c facetCounter) sorted() []FacetValue {
+	out := make([]FacetValue, 0, len(c))
+	for v, n := range c {
+		out = append(out, FacetValue{Value: v, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ni, errI := strconv.Atoi(strings.TrimSuffix(out[i].Value, "s"))
+		nj, errJ := strconv.Atoi(strings.TrimSuffix(out[j].Value, "s"))
+		if errI == nil && errJ == nil {
+			return ni < nj
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// artistFacetValues returns the buckets an artist belongs to for a facet.
+func artistFacetValues(art ArtistWithMeta, facet string) []string {
+	switch facet {
+	case facetCreationDecade:
+		if art.CreationDate > 0 {
+			return []string{decade(art.CreationDate)}
+		}
+	case facetMemberCount:
+		return []string{strconv.Itoa(len(art.Members))}
+	case facetFirstAlbumDecade:
+		if art.HasFirstAlbum() {
+			return []string{decade(art.FirstAlbumYear)}
+		}
+	case facetConcertCountry:
+		out := make([]string, 0, len(art.DatesLocations))
+		for slug := range art.DatesLocations {
+			out = append(out, countryCode(slug))
+		}
+		return out
+	case facetConcertYear:
+		out := make([]string, 0)
+		for _, dates := range art.DatesLocations {
+			for _, d := range dates {
+				if ts, err := parseAPIDate(d); err == nil {
+					out = append(out, strconv.Itoa(ts.Year()))
+				}
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// computeArtistFacets counts artists per bucket for each requested facet.
+func computeArtistFacets(artists []ArtistWithMeta, facets []string) map[string][]FacetValue {
+	out := make(map[string][]FacetValue, len(facets))
+	for _, facet := range facets {
+		counter := make(facetCounter)
+		for _, art := range artists {
+			counter.addOnce(artistFacetValues(art, facet))
+		}
+		out[facet] = counter.sorted()
+	}
+	return out
+}
+
+// computeEventFacets counts events per bucket for each requested facet.
+func computeEventFacets(events []Event, facets []string) map[string][]FacetValue {
+	out := make(map[string][]FacetValue, len(facets))
+	for _, facet := range facets {
+		counter := make(facetCounter)
+		for _, ev := range events {
+			switch facet {
+			case facetConcertCountry:
+				counter[countryCode(ev.Location)]++
+			case facetConcertYear:
+				counter[strconv.Itoa(ev.Date.Year())]++
+			}
+		}
+		out[facet] = counter.sorted()
+	}
+	return out
+}
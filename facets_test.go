@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputeArtistFacets(t *testing.T) {
+	artists := mergeArtists(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "A", Members: []string{"a", "b"}, CreationDate: 1991, FirstAlbum: "01-01-1993"},
+			{ID: 2, Name: "B", Members: []string{"c", "d"}, CreationDate: 1999, FirstAlbum: "01-01-2001"},
+			{ID: 3, Name: "C", Members: []string{"e"}, CreationDate: 2004},
+		},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{"paris-france": {"01-01-2019", "02-01-2019"}, "lyon-france": {"03-01-2019"}}},
+			{ID: 2, DatesLocations: map[string][]string{"berlin-germany": {"01-01-2020"}}},
+		},
+	})
+	facets := computeArtistFacets(artists, artistFacets)
+
+	want := map[string][]FacetValue{
+		facetCreationDecade:   {{"1990s", 2}, {"2000s", 1}},
+		facetMemberCount:      {{"1", 1}, {"2", 2}},
+		facetFirstAlbumDecade: {{"1990s", 1}, {"2000s", 1}},
+		facetConcertCountry:   {{"france", 1}, {"germany", 1}},
+		facetConcertYear:      {{"2019", 1}, {"2020", 1}},
+	}
+	if !reflect.DeepEqual(facets, want) {
+		t.Fatalf("facets = %+v, want %+v", facets, want)
+	}
+
+	if _, err := parseFacets("creationDecade,bogus", artistFacets); err == nil {
+		t.Fatalf("expected unknown facet to be rejected")
+	}
+	if got, _ := parseFacets("all", eventFacets); len(got) != len(eventFacets) {
+		t.Fatalf("expected all event facets, got %v", got)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFeedLimit = 50
+	maxFeedLimit     = 200
+)
+
+// feedNamespace is the UUID namespace of feed and entry IDs.
+var feedNamespace = [16]byte{0x6f, 0x1c, 0x2e, 0x8a, 0x4b, 0x57, 0x4d, 0x0e, 0x9a, 0x31, 0x52, 0xc4, 0x7d, 0x08, 0xe6, 0x93}
+
+// feedID derives a name-based (version 5) UUID URN, so an entry keeps the same
+// ID across refreshes and restarts.
+func feedID(name string) string {
+	h := sha1.New()
+	h.Write(feedNamespace[:])
+	io.WriteString(h, name)
+	sum := h.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// requestBaseURL returns scheme and host of the request, honouring a reverse
+// proxy's X-Forwarded-Proto.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// feedEntry is a format-neutral feed item.
+type feedEntry struct {
+	ID        string
+	Title     string
+	Link      string
+	Summary   string
+	Category  string
+	Published time.Time
+}
+
+// feed is a format-neutral feed.
+type feed struct {
+	ID      string
+	Title   string
+	Self    string
+	Link    string
+	Updated time.Time
+	Entries []feedEntry
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID        string        `xml:"id"`
+	Title     string        `xml:"title"`
+	Updated   string        `xml:"updated"`
+	Published string        `xml:"published"`
+	Link      atomLink      `xml:"link"`
+	Summary   string        `xml:"summary"`
+	Category  *atomCategory `xml:"category,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category,omitempty"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+func (f feed) atom() atomFeed {
+	out := atomFeed{
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Author:  "Groupie Tracker",
+		Links: []atomLink{
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, e := range f.Entries {
+		entry := atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Updated:   e.Published.UTC().Format(time.RFC3339),
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: e.Link, Rel: "alternate", Type: "text/html"},
+			Summary:   e.Summary,
+		}
+		if e.Category != "" {
+			entry.Category = &atomCategory{Term: e.Category}
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return out
+}
+
+func (f feed) rss() rssFeed {
+	channel := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   "Concerts récemment annoncés sur Groupie Tracker.",
+		LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		AtomLink:      atomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+	}
+	for _, e := range f.Entries {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+			Description: e.Summary,
+			Category:    e.Category,
+		})
+	}
+	return rssFeed{Version: "2.0", Channel: channel}
+}
+
+// handleConcertFeed serves /feeds/concerts.atom and /feeds/concerts.rss: the
+// concerts that appeared in the dataset on recent refreshes, newest first.
+func (a *App) handleConcertFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	format := strings.TrimPrefix(r.URL.Path, "/feeds/concerts.")
+	q := r.URL.Query()
+
+	limit := defaultFeedLimit
+	if v := strings.TrimSpace(q.Get("limit")); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxFeedLimit {
+			writeParamError(w, r, newParamError("limit", codeLimitOutOfRange, maxFeedLimit))
+			return
+		}
+		limit = parsed
+	}
+	// artist selects one artist by slug or ID; the other filters are those of /api/events.
+	artistKey := strings.TrimSpace(q.Get("artist"))
+	q.Del("artist")
+	filters, err := parseEventFilters(q, a.currentTime())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	base := requestBaseURL(r)
+	title := "Groupie Tracker – nouveaux concerts"
+	link := base + "/"
+	artistID := 0
+	if artistKey != "" {
+		art, ok := findArtist(a.cache.ArtistsWithMeta(), artistKey)
+		if !ok {
+			writeProblem(w, r, codeArtistNotFound)
+			return
+		}
+		artistID = art.ID
+		title = art.Name + " – nouveaux concerts"
+		link = base + "/artist/" + art.Slug
+	}
+	if filters.Country != "" {
+		title += " (" + filters.Country + ")"
+	}
+
+	slugs := make(map[int]string)
+	for _, art := range a.cache.ArtistsWithMeta() {
+		slugs[art.ID] = art.Slug
+	}
+	f := feed{
+		// The feed ID depends on the filters only, not on the host or format.
+		ID:    feedID(fmt.Sprintf("feed:concerts:%d:%s", artistID, canonicalFeedQuery(q))),
+		Title: title,
+		Self:  base + r.URL.RequestURI(),
+		Link:  link,
+	}
+	for _, ann := range a.cache.Announcements() {
+		if len(f.Entries) == limit {
+			break
+		}
+		ev := ann.Event
+		if artistID != 0 && ev.ArtistID != artistID {
+			continue
+		}
+		if !filters.Match(ev) {
+			continue
+		}
+		place := ev.City + ", " + ev.Country
+		f.Entries = append(f.Entries, feedEntry{
+			ID:        feedID("concert:" + eventUID(ev)),
+			Title:     fmt.Sprintf("%s – %s, %s", ev.ArtistName, place, ev.DateISO),
+			Link:      base + "/artist/" + slugs[ev.ArtistID],
+			Summary:   fmt.Sprintf("Nouveau concert de %s à %s le %s.", ev.ArtistName, place, ev.LocalDate),
+			Category:  ev.Country,
+			Published: ann.AnnouncedAt,
+		})
+		if ann.AnnouncedAt.After(f.Updated) {
+			f.Updated = ann.AnnouncedAt
+		}
+	}
+	// Without entries the feed changed when the data was last loaded.
+	if f.Updated.IsZero() {
+		f.Updated = a.cache.FetchedAt()
+	}
+	if f.Updated.IsZero() {
+		f.Updated = a.currentTime()
+	}
+
+	var doc interface{}
+	switch format {
+	case "atom":
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		doc = f.atom()
+	case "rss":
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		doc = f.rss()
+	default:
+		a.renderError(w, r, http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		log.Printf("encode feed: %v", err)
+	}
+}
+
+// canonicalFeedQuery lists the filters that shape a feed in a fixed order.
+func canonicalFeedQuery(q url.Values) string {
+	parts := make([]string, 0, 4)
+	for _, key := range []string{"country", "city", "year", "date_from", "date_to", "when", "tz"} {
+		if v := strings.TrimSpace(strings.Join(q[key], ",")); v != "" {
+			parts = append(parts, key+"="+normalizeText(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// feedTestApp loads a baseline with only Queen's London concerts, then the
+// full bundle, so Paris and Berlin are announced.
+func feedTestApp() *App {
+	app := newTestApp()
+	baseline := queenFloydBundle()
+	baseline.Relations = []Relation{
+		{ID: 1, DatesLocations: map[string][]string{"london-uk": {"01-01-1980", "02-01-1980"}}},
+	}
+	app.cache.Set(baseline)
+	app.cache.Set(queenFloydBundle())
+	return app
+}
+
+func TestAnnouncementsOnlyListNewConcerts(t *testing.T) {
+	app := feedTestApp()
+	got := app.cache.Announcements()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 new concerts, got %+v", got)
+	}
+	for _, ann := range got {
+		if ann.Event.Location == "london-uk" {
+			t.Fatalf("concert from the baseline was announced: %+v", ann)
+		}
+	}
+}
+
+func TestAtomFeedFiltersAndStableIDs(t *testing.T) {
+	app := feedTestApp()
+	fetch := func(target string) atomFeed {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		app.handleConcertFeed(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d (%s)", target, rr.Code, rr.Body.String())
+		}
+		if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+			t.Fatalf("unexpected content type %q", ct)
+		}
+		var doc atomFeed
+		if err := xml.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("decode feed: %v", err)
+		}
+		return doc
+	}
+
+	all := fetch("/feeds/concerts.atom")
+	if len(all.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", all.Entries)
+	}
+	queen := fetch("/feeds/concerts.atom?artist=queen")
+	if len(queen.Entries) != 1 || !strings.Contains(queen.Entries[0].Title, "Queen") {
+		t.Fatalf("unexpected artist feed %+v", queen.Entries)
+	}
+	germany := fetch("/feeds/concerts.atom?country=germany")
+	if len(germany.Entries) != 1 || !strings.Contains(germany.Entries[0].Title, "Pink Floyd") {
+		t.Fatalf("unexpected country feed %+v", germany.Entries)
+	}
+	if queen.ID == all.ID {
+		t.Fatal("filtered feeds need their own ID")
+	}
+
+	// A refresh with the same data keeps IDs, and the entry ID does not depend on the feed.
+	app.cache.Set(app.cache.Snapshot())
+	again := fetch("/feeds/concerts.atom?artist=1")
+	if again.ID != queen.ID || again.Entries[0].ID != queen.Entries[0].ID {
+		t.Fatalf("IDs changed: %s/%s vs %s/%s", again.ID, again.Entries[0].ID, queen.ID, queen.Entries[0].ID)
+	}
+	found := false
+	for _, e := range all.Entries {
+		found = found || e.ID == queen.Entries[0].ID
+	}
+	if !found || !strings.HasPrefix(queen.Entries[0].ID, "urn:uuid:") {
+		t.Fatalf("entry ID %s is not shared across feeds", queen.Entries[0].ID)
+	}
+}
+
+func TestRSSFeed(t *testing.T) {
+	app := feedTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/feeds/concerts.rss?limit=1", nil)
+	rr := httptest.NewRecorder()
+	app.handleConcertFeed(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	var doc rssFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode feed: %v", err)
+	}
+	if doc.Version != "2.0" || len(doc.Channel.Items) != 1 {
+		t.Fatalf("unexpected rss %s", rr.Body.String())
+	}
+	if item := doc.Channel.Items[0]; item.GUID.IsPermaLink || !strings.HasPrefix(item.GUID.Value, "urn:uuid:") {
+		t.Fatalf("unexpected guid %+v", item.GUID)
+	}
+}
+
+func TestFeedUnknownArtist(t *testing.T) {
+	app := feedTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/feeds/concerts.atom?artist=nobody", nil)
+	rr := httptest.NewRecorder()
+	app.handleConcertFeed(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
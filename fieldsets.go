@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Related resources include= can embed in an artist.
+const (
+	includeEvents    = "events"
+	includeLocations = "locations"
+)
+
+var artistIncludes = []string{includeEvents, includeLocations}
+
+// artistFields lists what fields= may select on the artist endpoints. Both
+// response shapes are accepted so one fieldset works whatever the source.
+var artistFields = jsonFieldNames(reflect.TypeOf(ArtistWithMeta{}), reflect.TypeOf(UnifiedArtist{}))
+
+// identityFields are always kept so a trimmed resource can still be linked.
+var identityFields = []string{"id", "slug"}
+
+// fieldSelection is a parsed fields/include pair. A nil Fields keeps every field.
+type fieldSelection struct {
+	Fields  map[string]bool
+	Include []string
+}
+
+// Active reports whether the response has to be reshaped.
+func (s fieldSelection) Active() bool {
+	return s.Fields != nil || len(s.Include) > 0
+}
+
+// jsonFieldNames returns the JSON keys of the given struct types, following
+// embedded structs the way encoding/json does.
+func jsonFieldNames(types ...reflect.Type) []string {
+	seen := make(map[string]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+				walk(f.Type)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			name := strings.Split(tag, ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			seen[name] = true
+		}
+	}
+	for _, t := range types {
+		walk(t)
+	}
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func splitList(value string) []string {
+	out := make([]string, 0)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// parseFieldSelection reads fields and include against what an endpoint
+// supports. Errors are *paramError.
+func parseFieldSelection(q url.Values, fields, includes []string) (fieldSelection, error) {
+	var sel fieldSelection
+	if requested := splitList(q.Get("fields")); len(requested) > 0 {
+		known := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			known[f] = true
+		}
+		sel.Fields = make(map[string]bool, len(requested)+len(identityFields))
+		for _, f := range requested {
+			if !known[f] {
+				return sel, newParamError("fields", codeUnknownField, f)
+			}
+			sel.Fields[f] = true
+		}
+		for _, f := range identityFields {
+			sel.Fields[f] = true
+		}
+	}
+	for _, inc := range splitList(q.Get("include")) {
+		ok := false
+		for _, known := range includes {
+			ok = ok || inc == known
+		}
+		if !ok {
+			return sel, newParamError("include", codeUnknownInclude, inc)
+		}
+		sel.Include = append(sel.Include, inc)
+	}
+	return sel, nil
+}
+
+// shapeResource encodes v, drops the fields that were not selected and adds
+// the embedded resources under "included".
+func shapeResource(v interface{}, sel fieldSelection, included map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(obj)+1)
+	for k, val := range obj {
+		if sel.Fields == nil || sel.Fields[k] {
+			out[k] = val
+		}
+	}
+	if included != nil {
+		out["included"] = included
+	}
+	return out, nil
+}
+
+// shapeList applies shapeResource to every item. artistID returns the
+// Groupie Tracker ID an item's embedded resources belong to, 0 for none.
+func shapeList[T any](items []T, sel fieldSelection, embed func(int) map[string]interface{}, artistID func(T) int) ([]map[string]interface{}, error) {
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		var included map[string]interface{}
+		if embed != nil {
+			if id := artistID(item); id > 0 {
+				included = embed(id)
+			}
+		}
+		shaped, err := shapeResource(item, sel, included)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, shaped)
+	}
+	return out, nil
+}
+
+// artistEmbedder returns the related resources of an artist for include=, or
+// nil when nothing is included. Lookups are grouped once per request.
+func (a *App) artistEmbedder(include []string) func(int) map[string]interface{} {
+	if len(include) == 0 {
+		return nil
+	}
+	events := make(map[int][]Event)
+	locations := make(map[int][]viewLocation)
+	for _, inc := range include {
+		switch inc {
+		case includeEvents:
+			for _, ev := range a.cache.Events() {
+				events[ev.ArtistID] = append(events[ev.ArtistID], ev)
+			}
+		case includeLocations:
+			for _, view := range buildLocationViews(a.cache.Snapshot()) {
+				locations[view.ArtistID] = append(locations[view.ArtistID], view)
+			}
+		}
+	}
+	return func(id int) map[string]interface{} {
+		out := make(map[string]interface{}, len(include))
+		for _, inc := range include {
+			switch inc {
+			case includeEvents:
+				out[inc] = append(make([]Event, 0), events[id]...)
+			case includeLocations:
+				out[inc] = append(make([]viewLocation, 0), locations[id]...)
+			}
+		}
+		return out
+	}
+}
+
+func artistWithMetaID(art ArtistWithMeta) int {
+	return art.ID
+}
+
+// unifiedArtistID only resolves Groupie Tracker artists; Spotify results have
+// no related resources.
+func unifiedArtistID(art UnifiedArtist) int {
+	if art.Source != sourceGroupie {
+		return 0
+	}
+	id, _ := strconv.Atoi(art.ID)
+	return id
+}
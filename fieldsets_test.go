@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fieldsetTestApp serves Queen alone from the shared bundle, with one member
+// and only the London concerts.
+func fieldsetTestApp() *App {
+	app := newTestApp()
+	bundle := queenFloydBundle()
+	bundle.Artists = bundle.Artists[:1]
+	bundle.Artists[0].Members = bundle.Artists[0].Members[:1]
+	bundle.Locations = []LocationIndex{{ID: 1, Locations: []string{"london-uk"}}}
+	bundle.Relations = []Relation{
+		{ID: 1, DatesLocations: map[string][]string{"london-uk": bundle.Relations[0].DatesLocations["london-uk"]}},
+	}
+	app.cache.Set(bundle)
+	return app
+}
+
+func TestArtistsSparseFieldset(t *testing.T) {
+	app := fieldsetTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?fields=name,image", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 artist, got %d", len(got))
+	}
+	for _, key := range []string{"id", "slug", "name", "image"} {
+		if _, ok := got[0][key]; !ok {
+			t.Fatalf("expected %q in %v", key, got[0])
+		}
+	}
+	if len(got[0]) != 4 {
+		t.Fatalf("expected only selected fields, got %v", got[0])
+	}
+}
+
+func TestArtistByIDIncludesEvents(t *testing.T) {
+	app := fieldsetTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/artists/queen?fields=name&include=events,locations", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtistByID(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	var got struct {
+		Name     string `json:"name"`
+		Members  []string
+		Included struct {
+			Events    []Event        `json:"events"`
+			Locations []viewLocation `json:"locations"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Name != "Queen" || got.Members != nil {
+		t.Fatalf("unexpected fields %s", rr.Body.String())
+	}
+	if len(got.Included.Events) != 2 || len(got.Included.Locations) != 1 {
+		t.Fatalf("unexpected included resources %s", rr.Body.String())
+	}
+}
+
+func TestFieldsetRejectsUnknownNames(t *testing.T) {
+	app := fieldsetTestApp()
+	for _, target := range []string{"/api/artists?fields=name,altitude", "/api/artists?include=albums"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		app.handleAPIArtists(rr, req)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"param"`) {
+			t.Fatalf("GET %s: expected 400 with param, got %d %s", target, rr.Code, rr.Body.String())
+		}
+	}
+}
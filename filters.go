@@ -0,0 +1,251 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// artistFilters holds the parsed filter parameters of /api/artists.
+type artistFilters struct {
+	Name      string
+	Member    string
+	Year      int
+	Creation  intRange
+	Album     intRange
+	Members   intRange
+	Dates     dateRange
+	Fuzzy     bool
+	Threshold float64
+	// IDs restricts the results to these artists, in this order.
+	IDs []int
+}
+
+// maxArtistIDs bounds the ids filter.
+const maxArtistIDs = 100
+
+// parseArtistFilters reads the artist filter parameters. Errors are *paramError.
+func parseArtistFilters(q url.Values) (artistFilters, error) {
+	f := artistFilters{
+		Name:      strings.TrimSpace(q.Get("name")),
+		Member:    strings.TrimSpace(q.Get("member")),
+		Fuzzy:     parseBool(q.Get("fuzzy")),
+		Threshold: defaultFuzzyThreshold,
+	}
+	var err error
+	if f.Year, err = parseYear(q.Get("year")); err != nil {
+		return f, newParamError("year", codeInvalidYear)
+	}
+	if f.Creation, err = parseIntRange(q, "creation_from", "creation_to"); err != nil {
+		return f, err
+	}
+	if f.Album, err = parseYearRange(q, "album_from", "album_to"); err != nil {
+		return f, err
+	}
+	if f.Members, err = parseIntRange(q, "members_min", "members_max"); err != nil {
+		return f, err
+	}
+	if f.Dates, err = parseDateRange(q, "date_from", "date_to"); err != nil {
+		return f, err
+	}
+	if raw := strings.TrimSpace(q.Get("threshold")); raw != "" {
+		f.Threshold, err = strconv.ParseFloat(raw, 64)
+		if err != nil || f.Threshold <= 0 || f.Threshold > 1 {
+			return f, newParamError("threshold", codeInvalidThreshold)
+		}
+		f.Fuzzy = true
+	}
+	if f.IDs, err = parseArtistIDs(q.Get("ids")); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseArtistIDs reads a comma list of artist IDs. Typed v2 IDs (groupie:1)
+// are accepted too; duplicates are dropped.
+func parseArtistIDs(raw string) ([]int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxArtistIDs {
+		return nil, newParamError("ids", codeInvalidIDs, maxArtistIDs)
+	}
+	ids := make([]int, 0, len(parts))
+	seen := make(map[int]bool, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(part), sourceGroupie+":"))
+		if err != nil || id <= 0 {
+			return nil, newParamError("ids", codeInvalidIDs, maxArtistIDs)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Match reports whether art passes every filter. In fuzzy mode the returned
+// artist carries its MatchScore.
+func (f artistFilters) Match(art ArtistWithMeta) (ArtistWithMeta, bool) {
+	if f.Fuzzy && (f.Name != "" || f.Member != "") {
+		score, ok := fuzzyArtistScore(art, f.Name, f.Member, f.Threshold)
+		if !ok {
+			return art, false
+		}
+		art.MatchScore = &score
+	} else {
+		if f.Name != "" && !textContains(art.Name, f.Name) {
+			return art, false
+		}
+		if f.Member != "" && !containsMember(art.Members, f.Member) {
+			return art, false
+		}
+	}
+	if f.Year > 0 && art.CreationDate != f.Year {
+		return art, false
+	}
+	if !f.Creation.Contains(art.CreationDate) {
+		return art, false
+	}
+	if f.Album.Active() && (!art.HasFirstAlbum() || !f.Album.Contains(art.FirstAlbumYear)) {
+		return art, false
+	}
+	if !f.Members.Contains(len(art.Members)) {
+		return art, false
+	}
+	if f.Dates.Active() && !artistPlayedBetween(art, f.Dates) {
+		return art, false
+	}
+	return art, true
+}
+
+// artistPlayedBetween reports whether the artist has at least one concert in r.
+func artistPlayedBetween(art ArtistWithMeta, r dateRange) bool {
+	for _, dates := range art.DatesLocations {
+		for _, d := range dates {
+			if ts, err := parseAPIDate(d); err == nil && r.Contains(ts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterArtists applies f to artists and returns the matches in input order,
+// or in the order of f.IDs when set.
+func filterArtists(artists []ArtistWithMeta, f artistFilters) []ArtistWithMeta {
+	if f.IDs != nil {
+		byID := make(map[int]ArtistWithMeta, len(artists))
+		for _, art := range artists {
+			byID[art.ID] = art
+		}
+		ordered := make([]ArtistWithMeta, 0, len(f.IDs))
+		for _, id := range f.IDs {
+			if art, ok := byID[id]; ok {
+				ordered = append(ordered, art)
+			}
+		}
+		artists = ordered
+	}
+	out := make([]ArtistWithMeta, 0, len(artists))
+	for _, art := range artists {
+		if matched, ok := f.Match(art); ok {
+			out = append(out, matched)
+		}
+	}
+	return out
+}
+
+// missingArtistIDs returns the requested IDs that match no artist.
+func missingArtistIDs(artists []ArtistWithMeta, ids []int) []int {
+	known := make(map[int]bool, len(artists))
+	for _, art := range artists {
+		known[art.ID] = true
+	}
+	var missing []int
+	for _, id := range ids {
+		if !known[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// eventFilters holds the parsed filter parameters of /api/events.
+type eventFilters struct {
+	Country string
+	City    string
+	Artist  string
+	Year    int
+	Dates   dateRange
+	Window  string
+	UserLoc *time.Location
+	Now     time.Time
+}
+
+// parseEventFilters reads the event filter parameters. Errors are *paramError.
+func parseEventFilters(q url.Values, now time.Time) (eventFilters, error) {
+	f := eventFilters{
+		Country: q.Get("country"),
+		City:    q.Get("city"),
+		Artist:  q.Get("artist"),
+		Window:  strings.ToLower(strings.TrimSpace(q.Get("when"))),
+		UserLoc: time.UTC,
+		Now:     now,
+	}
+	var err error
+	if f.Year, err = parseYear(q.Get("year")); err != nil {
+		return f, newParamError("year", codeInvalidYear)
+	}
+	if f.Dates, err = parseDateRange(q, "date_from", "date_to"); err != nil {
+		return f, err
+	}
+	if tz := strings.TrimSpace(q.Get("tz")); tz != "" {
+		if f.UserLoc, err = time.LoadLocation(tz); err != nil {
+			return f, newParamError("tz", codeInvalidTimeZone)
+		}
+	}
+	switch f.Window {
+	case "", windowToday, windowUpcoming, windowPast:
+	default:
+		return f, newParamError("when", codeInvalidWindow)
+	}
+	return f, nil
+}
+
+// Match reports whether ev passes every filter.
+func (f eventFilters) Match(ev Event) bool {
+	if !textContains(ev.Country, f.Country) {
+		return false
+	}
+	if !textContains(ev.City, f.City) {
+		return false
+	}
+	if !textContains(ev.ArtistName, f.Artist) {
+		return false
+	}
+	if f.Year > 0 && ev.Date.Year() != f.Year {
+		return false
+	}
+	if !f.Dates.Contains(ev.Date) {
+		return false
+	}
+	if f.Window != "" && !ev.inWindow(f.Window, f.Now, f.UserLoc) {
+		return false
+	}
+	return true
+}
+
+// filterEvents applies f to events and returns the matches in input order.
+func filterEvents(events []Event, f eventFilters) []Event {
+	out := make([]Event, 0, len(events))
+	for _, ev := range events {
+		if f.Match(ev) {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
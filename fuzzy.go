@@ -0,0 +1,88 @@
+package main
+
+import "strings"
+
+// defaultFuzzyThreshold is the minimum similarity accepted when fuzzy matching is on.
+const defaultFuzzyThreshold = 0.7
+
+// levenshtein returns the edit distance between a and b, counted in runes.
+func levenshtein(a, b []rune) int {
+	if len(a) == 0 {
+		return len(b)
+	}
+	if len(b) == 0 {
+		return len(a)
+	}
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// editSimilarity maps the edit distance onto [0, 1], 1 meaning identical strings.
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// fuzzyScore rates how well query matches text, from 0 to 1. A substring match
+// scores 1 so plain filters keep working; otherwise the query is compared with
+// the whole text and with every run of consecutive words of the same length,
+// which lets "pink floid" match "Pink Floyd" and "quen" match "Queen".
+func fuzzyScore(query, text string) float64 {
+	q := strings.Join(tokenize(query), " ")
+	t := strings.Join(tokenize(text), " ")
+	if q == "" {
+		return 1
+	}
+	if strings.Contains(t, q) {
+		return 1
+	}
+	best := editSimilarity(q, t)
+	words := strings.Fields(t)
+	size := len(strings.Fields(q))
+	for i := 0; i+size <= len(words); i++ {
+		if s := editSimilarity(q, strings.Join(words[i:i+size], " ")); s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// bestFuzzyScore returns the highest score of query against any of texts.
+func bestFuzzyScore(query string, texts []string) float64 {
+	best := 0.0
+	for _, t := range texts {
+		if s := fuzzyScore(query, t); s > best {
+			best = s
+		}
+	}
+	return best
+}
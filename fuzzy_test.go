@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		min, max    float64
+	}{
+		{"Quen", "Queen", 0.8, 0.8},
+		{"Pink Floid", "Pink Floyd", 0.9, 0.9},
+		{"Xxxtentacion", "XXXTentacion", 1, 1},
+		{"floid", "Pink Floyd", 0.8, 0.8},
+		{"metallica", "ABBA", 0, 0.3},
+	}
+	for _, tt := range tests {
+		got := fuzzyScore(tt.query, tt.text)
+		if got < tt.min-1e-9 || got > tt.max+1e-9 {
+			t.Errorf("fuzzyScore(%q, %q) = %.2f, want between %.2f and %.2f", tt.query, tt.text, got, tt.min, tt.max)
+		}
+	}
+}
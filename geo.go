@@ -0,0 +1,222 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// CountrySummary aggregates the concerts held in one country.
+// Code is the country part of the upstream location slugs (e.g. "usa", "germany").
+type CountrySummary struct {
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	ArtistCount  int    `json:"artistCount"`
+	ConcertCount int    `json:"concertCount"`
+	CityCount    int    `json:"cityCount"`
+	FirstDate    string `json:"firstDate,omitempty"`
+	LastDate     string `json:"lastDate,omitempty"`
+}
+
+// CountryDetail adds the cities and artists behind a CountrySummary.
+type CountryDetail struct {
+	CountrySummary
+	Cities  []CitySummary `json:"cities"`
+	Artists []AreaArtist  `json:"artists"`
+}
+
+// CitySummary aggregates the concerts held in one city. Slug is the upstream
+// location slug (e.g. "los_angeles-usa") which is unique across countries.
+type CitySummary struct {
+	Slug         string `json:"slug"`
+	Name         string `json:"name"`
+	Country      string `json:"country"`
+	CountryCode  string `json:"countryCode"`
+	ArtistCount  int    `json:"artistCount"`
+	ConcertCount int    `json:"concertCount"`
+	FirstDate    string `json:"firstDate,omitempty"`
+	LastDate     string `json:"lastDate,omitempty"`
+}
+
+// CityDetail lists every artist and dated concert of a city.
+type CityDetail struct {
+	CitySummary
+	Artists  []AreaArtist `json:"artists"`
+	Concerts []Event      `json:"concerts"`
+}
+
+// AreaArtist is an artist that played in a country or city.
+type AreaArtist struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Slug         string `json:"slug"`
+	ConcertCount int    `json:"concertCount"`
+}
+
+// countryCode extracts the country part of a location slug.
+func countryCode(slug string) string {
+	parts := strings.Split(strings.ToLower(slug), "-")
+	return parts[len(parts)-1]
+}
+
+// areaStats accumulates counts and date ranges for a set of events.
+type areaStats struct {
+	concerts int
+	artists  map[int]int
+	cities   map[string]bool
+	first    time.Time
+	last     time.Time
+}
+
+func newAreaStats() *areaStats {
+	return &areaStats{artists: make(map[int]int), cities: make(map[string]bool)}
+}
+
+func (s *areaStats) add(ev Event) {
+	s.concerts++
+	s.artists[ev.ArtistID]++
+	s.cities[ev.Location] = true
+	if s.first.IsZero() || ev.Date.Before(s.first) {
+		s.first = ev.Date
+	}
+	if ev.Date.After(s.last) {
+		s.last = ev.Date
+	}
+}
+
+func (s *areaStats) dateRange() (string, string) {
+	if s.concerts == 0 {
+		return "", ""
+	}
+	return s.first.Format("2006-01-02"), s.last.Format("2006-01-02")
+}
+
+// areaArtists lists the artists counted in stats, most active first.
+func (s *areaStats) areaArtists(artists []ArtistWithMeta) []AreaArtist {
+	out := make([]AreaArtist, 0, len(s.artists))
+	for _, art := range artists {
+		if n, ok := s.artists[art.ID]; ok {
+			out = append(out, AreaArtist{ID: art.ID, Name: art.Name, Slug: art.Slug, ConcertCount: n})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].ConcertCount != out[j].ConcertCount {
+			return out[i].ConcertCount > out[j].ConcertCount
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func (s *areaStats) countrySummary(code string) CountrySummary {
+	first, last := s.dateRange()
+	return CountrySummary{
+		Code:         code,
+		Name:         titleCase(strings.ReplaceAll(code, "_", " ")),
+		ArtistCount:  len(s.artists),
+		ConcertCount: s.concerts,
+		CityCount:    len(s.cities),
+		FirstDate:    first,
+		LastDate:     last,
+	}
+}
+
+func (s *areaStats) citySummary(slug string) CitySummary {
+	first, last := s.dateRange()
+	name := splitLocationSlug(slug)
+	return CitySummary{
+		Slug:         slug,
+		Name:         name.City,
+		Country:      name.Country,
+		CountryCode:  countryCode(slug),
+		ArtistCount:  len(s.artists),
+		ConcertCount: s.concerts,
+		FirstDate:    first,
+		LastDate:     last,
+	}
+}
+
+// groupEvents buckets events by the key returned for each event.
+func groupEvents(events []Event, key func(Event) string) (map[string]*areaStats, []string) {
+	stats := make(map[string]*areaStats)
+	keys := make([]string, 0)
+	for _, ev := range events {
+		k := key(ev)
+		st, ok := stats[k]
+		if !ok {
+			st = newAreaStats()
+			stats[k] = st
+			keys = append(keys, k)
+		}
+		st.add(ev)
+	}
+	sort.Strings(keys)
+	return stats, keys
+}
+
+func eventCountry(ev Event) string { return countryCode(ev.Location) }
+func eventCity(ev Event) string    { return ev.Location }
+
+// summarizeCountries returns one summary per country, sorted by code.
+func summarizeCountries(events []Event) []CountrySummary {
+	stats, codes := groupEvents(events, eventCountry)
+	out := make([]CountrySummary, 0, len(codes))
+	for _, code := range codes {
+		out = append(out, stats[code].countrySummary(code))
+	}
+	return out
+}
+
+// summarizeCities returns one summary per city, sorted by slug.
+func summarizeCities(events []Event) []CitySummary {
+	stats, slugs := groupEvents(events, eventCity)
+	out := make([]CitySummary, 0, len(slugs))
+	for _, slug := range slugs {
+		out = append(out, stats[slug].citySummary(slug))
+	}
+	return out
+}
+
+// buildCountryDetail aggregates a single country; ok is false when no concert matches.
+func buildCountryDetail(code string, artists []ArtistWithMeta, events []Event) (CountryDetail, bool) {
+	code = strings.ToLower(code)
+	matching := make([]Event, 0)
+	for _, ev := range events {
+		if eventCountry(ev) == code {
+			matching = append(matching, ev)
+		}
+	}
+	if len(matching) == 0 {
+		return CountryDetail{}, false
+	}
+	st := newAreaStats()
+	for _, ev := range matching {
+		st.add(ev)
+	}
+	return CountryDetail{
+		CountrySummary: st.countrySummary(code),
+		Cities:         summarizeCities(matching),
+		Artists:        st.areaArtists(artists),
+	}, true
+}
+
+// buildCityDetail aggregates a single city; ok is false when no concert matches.
+func buildCityDetail(slug string, artists []ArtistWithMeta, events []Event) (CityDetail, bool) {
+	slug = strings.ToLower(slug)
+	st := newAreaStats()
+	concerts := make([]Event, 0)
+	for _, ev := range events {
+		if ev.Location == slug {
+			st.add(ev)
+			concerts = append(concerts, ev)
+		}
+	}
+	if len(concerts) == 0 {
+		return CityDetail{}, false
+	}
+	return CityDetail{
+		CitySummary: st.citySummary(slug),
+		Artists:     st.areaArtists(artists),
+		Concerts:    concerts,
+	}, true
+}
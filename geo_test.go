@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestCountryAndCityAggregates(t *testing.T) {
+	bundle := DataBundle{
+		Artists: []Artist{{ID: 1, Name: "Alpha"}, {ID: 2, Name: "Beta"}},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{
+				"berlin-germany":  {"01-05-2019", "02-05-2019"},
+				"hamburg-germany": {"05-05-2019"},
+			}},
+			{ID: 2, DatesLocations: map[string][]string{
+				"berlin-germany": {"10-10-2021"},
+				"paris-france":   {"11-10-2021"},
+			}},
+		},
+	}
+	artists := mergeArtists(bundle)
+	events := buildEvents(bundle.Artists, bundle.Relations)
+
+	countries := summarizeCountries(events)
+	if len(countries) != 2 || countries[1].Code != "germany" {
+		t.Fatalf("unexpected countries %+v", countries)
+	}
+	de := countries[1]
+	if de.ArtistCount != 2 || de.ConcertCount != 4 || de.CityCount != 2 {
+		t.Fatalf("unexpected germany counts %+v", de)
+	}
+	if de.FirstDate != "2019-05-01" || de.LastDate != "2021-10-10" {
+		t.Fatalf("unexpected germany range %+v", de)
+	}
+
+	city, ok := buildCityDetail("berlin-germany", artists, events)
+	if !ok || city.Name != "Berlin" || len(city.Concerts) != 3 {
+		t.Fatalf("unexpected city detail %+v", city)
+	}
+	if city.Artists[0].Slug != "alpha" || city.Artists[0].ConcertCount != 2 {
+		t.Fatalf("expected most active artist first, got %+v", city.Artists)
+	}
+	if _, ok := buildCountryDetail("atlantis", artists, events); ok {
+		t.Fatalf("expected unknown country to be missing")
+	}
+}
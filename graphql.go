@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxGraphQLBody caps the size of a POSTed GraphQL request.
+const maxGraphQLBody = 64 << 10
+
+// gqlData indexes one cache snapshot for the resolvers of a request.
+type gqlData struct {
+	artists           []ArtistWithMeta
+	artistByID        map[int]*ArtistWithMeta
+	events            []Event
+	eventsByArtist    map[int][]Event
+	locations         []viewLocation
+	locationsByArtist map[int][]viewLocation
+	members           []Member
+	memberByName      map[string]*Member
+}
+
+// data builds the per-request index on first use, so introspection-only
+// queries never touch the cache.
+func (ec *gqlExec) data() *gqlData {
+	if ec.store != nil {
+		return ec.store
+	}
+	ec.app.ensureCache(ec.ctx)
+	// Every view comes from one snapshot, so a refresh during the request
+	// cannot mix artists of one version with events of the next.
+	snap := ec.app.cache.Snapshot()
+	d := &gqlData{
+		artists:           mergeArtists(snap),
+		events:            buildEvents(snap.Artists, snap.Relations),
+		locations:         buildLocationViews(snap),
+		members:           buildMembers(snap.Artists),
+		eventsByArtist:    make(map[int][]Event),
+		locationsByArtist: make(map[int][]viewLocation),
+	}
+	d.artistByID = make(map[int]*ArtistWithMeta, len(d.artists))
+	for i := range d.artists {
+		id := d.artists[i].ID
+		d.artistByID[id] = &d.artists[i]
+		// Non-null list fields need [] rather than nil for artists without concerts.
+		d.eventsByArtist[id] = []Event{}
+		d.locationsByArtist[id] = []viewLocation{}
+	}
+	for _, ev := range d.events {
+		d.eventsByArtist[ev.ArtistID] = append(d.eventsByArtist[ev.ArtistID], ev)
+	}
+	for _, loc := range d.locations {
+		d.locationsByArtist[loc.ArtistID] = append(d.locationsByArtist[loc.ArtistID], loc)
+	}
+	d.memberByName = make(map[string]*Member, len(d.members))
+	for i := range d.members {
+		d.memberByName[d.members[i].NormalizedName] = &d.members[i]
+	}
+	ec.store = d
+	return d
+}
+
+// argValues converts GraphQL arguments back to the query parameters of the
+// REST endpoints, so both share the same parsing and filtering.
+func argValues(args map[string]interface{}, names map[string]string) url.Values {
+	q := make(url.Values)
+	for arg, param := range names {
+		switch v := args[arg].(type) {
+		case nil:
+		case string:
+			q.Set(param, v)
+		case int:
+			q.Set(param, strconv.Itoa(v))
+		case float64:
+			q.Set(param, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			q.Set(param, strconv.FormatBool(v))
+		}
+	}
+	return q
+}
+
+// pageArgs applies offset and limit arguments to a result count.
+func pageArgs(args map[string]interface{}, total int) (int, int, error) {
+	offset, _ := args["offset"].(int)
+	if offset < 0 {
+		return 0, 0, newParamError("offset", codeInvalidOffset)
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit, ok := args["limit"].(int); ok {
+		if limit < 0 || limit > maxPageLimit {
+			return 0, 0, newParamError("limit", codeInvalidLimit)
+		}
+		if offset+limit < end {
+			end = offset + limit
+		}
+	}
+	return offset, end, nil
+}
+
+func stringArg(name, description string) *gqlArg {
+	return &gqlArg{Name: name, Description: description, Type: gqlStringT}
+}
+
+func intArg(name, description string) *gqlArg {
+	return &gqlArg{Name: name, Description: description, Type: gqlIntT}
+}
+
+func pageGQLArgs() []*gqlArg {
+	return []*gqlArg{
+		intArg("limit", "Maximum number of results."),
+		{Name: "offset", Description: "Number of results to skip.", Type: gqlIntT, Default: 0, DefaultLiteral: formatLiteral(0)},
+	}
+}
+
+// nullableInt maps the zero value to null.
+func nullableInt(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+func nullableText(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+// newGraphQLSchema declares the public schema. Types are created first and
+// their fields filled in afterwards because they reference each other.
+func newGraphQLSchema() *gqlSchema {
+	artistT := &gqlType{Kind: gqlKindObject, Name: "Artist", Description: "A band or solo artist from the Groupie Tracker API."}
+	memberT := &gqlType{Kind: gqlKindObject, Name: "Member", Description: "A person playing in one or more bands."}
+	locationT := &gqlType{Kind: gqlKindObject, Name: "Location", Description: "A place where an artist played."}
+	eventT := &gqlType{Kind: gqlKindObject, Name: "Event", Description: "One dated concert."}
+	spotifyT := &gqlType{Kind: gqlKindObject, Name: "SpotifyArtist", Description: "An artist as described by Spotify."}
+	windowT := &gqlType{Kind: gqlKindEnum, Name: "EventWindow", Description: "Time window relative to now.", EnumValues: []gqlEnumValue{
+		{Name: "TODAY", Description: "Concerts taking place today."},
+		{Name: "UPCOMING", Description: "Concerts that have not ended yet."},
+		{Name: "PAST", Description: "Concerts that have ended."},
+	}}
+
+	artistOf := func(ec *gqlExec, id int) (interface{}, error) {
+		if art, ok := ec.data().artistByID[id]; ok {
+			return art, nil
+		}
+		return nil, nil
+	}
+
+	artistT.Fields = []*gqlField{
+		{Name: "id", Type: nonNull(gqlIntT), Resolve: prop(func(a *ArtistWithMeta) interface{} { return a.ID })},
+		{Name: "slug", Type: nonNull(gqlStringT), Resolve: prop(func(a *ArtistWithMeta) interface{} { return a.Slug })},
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(a *ArtistWithMeta) interface{} { return a.Name })},
+		{Name: "image", Type: nonNull(gqlStringT), Resolve: prop(func(a *ArtistWithMeta) interface{} { return a.Image })},
+		{Name: "creationDate", Type: gqlIntT, Resolve: prop(func(a *ArtistWithMeta) interface{} { return nullableInt(a.CreationDate) })},
+		{Name: "firstAlbum", Type: gqlStringT, Description: "First album date as published upstream (DD-MM-YYYY).", Resolve: prop(func(a *ArtistWithMeta) interface{} { return nullableText(a.FirstAlbum) })},
+		{Name: "firstAlbumDate", Type: gqlStringT, Description: "First album date as YYYY-MM-DD.", Resolve: prop(func(a *ArtistWithMeta) interface{} { return nullableText(a.FirstAlbumISO) })},
+		{Name: "firstAlbumYear", Type: gqlIntT, Resolve: prop(func(a *ArtistWithMeta) interface{} { return nullableInt(a.FirstAlbumYear) })},
+		{Name: "yearsToFirstAlbum", Type: gqlIntT, Resolve: prop(func(a *ArtistWithMeta) interface{} {
+			if a.YearsToFirstAlbum == nil {
+				return nil
+			}
+			return *a.YearsToFirstAlbum
+		})},
+		{Name: "members", Type: nonNull(listOf(nonNull(memberT))), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			art := source.(*ArtistWithMeta)
+			out := make([]*Member, 0, len(art.Members))
+			for _, name := range art.Members {
+				if m, ok := ec.data().memberByName[normalizeMemberName(name)]; ok {
+					out = append(out, m)
+				}
+			}
+			return out, nil
+		}},
+		{Name: "locations", Type: nonNull(listOf(nonNull(locationT))), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return ec.data().locationsByArtist[source.(*ArtistWithMeta).ID], nil
+		}},
+		{
+			Name: "events", Type: nonNull(listOf(nonNull(eventT))), Description: "Concerts in chronological order.",
+			Args: append([]*gqlArg{{Name: "when", Type: windowT}}, pageGQLArgs()...),
+			Resolve: func(ec *gqlExec, source interface{}, args map[string]interface{}) (interface{}, error) {
+				events := ec.data().eventsByArtist[source.(*ArtistWithMeta).ID]
+				if when, ok := args["when"].(string); ok {
+					filtered := make([]Event, 0, len(events))
+					now := ec.app.currentTime()
+					for _, ev := range events {
+						if ev.inWindow(strings.ToLower(when), now, time.UTC) {
+							filtered = append(filtered, ev)
+						}
+					}
+					events = filtered
+				}
+				start, end, err := pageArgs(args, len(events))
+				if err != nil {
+					return nil, err
+				}
+				return events[start:end], nil
+			},
+		},
+	}
+
+	memberT.Fields = []*gqlField{
+		{Name: "id", Type: nonNull(gqlIDT), Resolve: prop(func(m *Member) interface{} { return m.ID })},
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(m *Member) interface{} { return m.Name })},
+		{Name: "artists", Type: nonNull(listOf(nonNull(artistT))), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			m := source.(*Member)
+			out := make([]*ArtistWithMeta, 0, len(m.Bands))
+			for _, band := range m.Bands {
+				if art, ok := ec.data().artistByID[band.ArtistID]; ok {
+					out = append(out, art)
+				}
+			}
+			return out, nil
+		}},
+	}
+
+	locationT.Fields = []*gqlField{
+		{Name: "slug", Type: nonNull(gqlStringT), Description: "Upstream location, e.g. los_angeles-usa.", Resolve: prop(func(l viewLocation) interface{} { return l.Raw })},
+		{Name: "city", Type: nonNull(gqlStringT), Resolve: prop(func(l viewLocation) interface{} { return l.City })},
+		{Name: "country", Type: nonNull(gqlStringT), Resolve: prop(func(l viewLocation) interface{} { return l.Country })},
+		{Name: "countryCode", Type: nonNull(gqlStringT), Resolve: prop(func(l viewLocation) interface{} { return countryCode(l.Raw) })},
+		{Name: "eventCount", Type: nonNull(gqlIntT), Resolve: prop(func(l viewLocation) interface{} { return l.EventCount })},
+		{Name: "artist", Type: nonNull(artistT), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return artistOf(ec, source.(viewLocation).ArtistID)
+		}},
+		{Name: "events", Type: nonNull(listOf(nonNull(eventT))), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			loc := source.(viewLocation)
+			out := make([]Event, 0, loc.EventCount)
+			for _, ev := range ec.data().eventsByArtist[loc.ArtistID] {
+				if ev.Location == loc.Raw {
+					out = append(out, ev)
+				}
+			}
+			return out, nil
+		}},
+	}
+
+	eventT.Fields = []*gqlField{
+		{Name: "date", Type: nonNull(gqlStringT), Description: "Concert day as YYYY-MM-DD.", Resolve: prop(func(e Event) interface{} { return e.DateISO })},
+		{Name: "localDate", Type: nonNull(gqlStringT), Resolve: prop(func(e Event) interface{} { return e.LocalDate })},
+		{Name: "timeZone", Type: nonNull(gqlStringT), Resolve: prop(func(e Event) interface{} { return e.TimeZone })},
+		{Name: "startsAt", Type: nonNull(gqlStringT), Description: "Start instant in RFC 3339, UTC.", Resolve: prop(func(e Event) interface{} { return e.StartsAt.UTC().Format(time.RFC3339) })},
+		{Name: "city", Type: nonNull(gqlStringT), Resolve: prop(func(e Event) interface{} { return e.City })},
+		{Name: "country", Type: nonNull(gqlStringT), Resolve: prop(func(e Event) interface{} { return e.Country })},
+		{Name: "location", Type: nonNull(locationT), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			ev := source.(Event)
+			for _, loc := range ec.data().locationsByArtist[ev.ArtistID] {
+				if loc.Raw == ev.Location {
+					return loc, nil
+				}
+			}
+			name := splitLocationSlug(ev.Location)
+			return viewLocation{ArtistID: ev.ArtistID, ArtistName: ev.ArtistName, City: name.City, Country: name.Country, Raw: name.Raw}, nil
+		}},
+		{Name: "artist", Type: nonNull(artistT), Resolve: func(ec *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return artistOf(ec, source.(Event).ArtistID)
+		}},
+	}
+
+	spotifyT.Fields = []*gqlField{
+		{Name: "id", Type: nonNull(gqlIDT), Resolve: prop(func(s spotifyArtistDetail) interface{} { return s.ID })},
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(s spotifyArtistDetail) interface{} { return s.Name })},
+		{Name: "imageUrl", Type: gqlStringT, Resolve: prop(func(s spotifyArtistDetail) interface{} { return nullableText(s.ImageURL) })},
+		{Name: "genres", Type: nonNull(listOf(nonNull(gqlStringT))), Resolve: prop(func(s spotifyArtistDetail) interface{} { return append([]string{}, s.Genres...) })},
+		{Name: "popularity", Type: nonNull(gqlIntT), Resolve: prop(func(s spotifyArtistDetail) interface{} { return s.Popularity })},
+		{Name: "followers", Type: nonNull(gqlIntT), Resolve: prop(func(s spotifyArtistDetail) interface{} { return s.Followers })},
+	}
+
+	artistFilterArgs := map[string]string{
+		"name": "name", "member": "member", "year": "year",
+		"creationFrom": "creation_from", "creationTo": "creation_to",
+		"albumFrom": "album_from", "albumTo": "album_to",
+		"membersMin": "members_min", "membersMax": "members_max",
+		"dateFrom": "date_from", "dateTo": "date_to",
+		"fuzzy": "fuzzy", "threshold": "threshold", "sort": "sort",
+	}
+	eventFilterArgs := map[string]string{
+		"country": "country", "city": "city", "artist": "artist", "year": "year",
+		"dateFrom": "date_from", "dateTo": "date_to", "when": "when", "tz": "tz",
+	}
+
+	query := &gqlType{Kind: gqlKindObject, Name: "Query", Description: "Entry points of the API."}
+	query.Fields = []*gqlField{
+		{
+			Name: "artists", Type: nonNull(listOf(nonNull(artistT))),
+			Description: "Artists matching every filter, with the same semantics as /api/artists.",
+			Args: append([]*gqlArg{
+				stringArg("name", "Artist name contains this text (accents and case ignored)."),
+				stringArg("member", "A member name contains this text."),
+				intArg("year", "Creation year."),
+				intArg("creationFrom", "Minimum creation year."),
+				intArg("creationTo", "Maximum creation year."),
+				intArg("albumFrom", "Minimum first album year."),
+				intArg("albumTo", "Maximum first album year."),
+				intArg("membersMin", "Minimum number of members."),
+				intArg("membersMax", "Maximum number of members."),
+				stringArg("dateFrom", "Has a concert on or after this day (YYYY-MM-DD)."),
+				stringArg("dateTo", "Has a concert on or before this day (YYYY-MM-DD)."),
+				{Name: "fuzzy", Description: "Typo tolerant name and member matching.", Type: gqlBooleanT},
+				{Name: "threshold", Description: "Minimum fuzzy similarity between 0 and 1; implies fuzzy.", Type: gqlFloatT},
+				stringArg("sort", "name, creationDate, firstAlbum, yearsToFirstAlbum or matchScore; prefix with - for descending."),
+			}, pageGQLArgs()...),
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				q := argValues(args, artistFilterArgs)
+				filters, err := parseArtistFilters(q)
+				if err != nil {
+					return nil, err
+				}
+				filtered := filterArtists(ec.data().artists, filters)
+				sortSpec := q.Get("sort")
+				if filters.Fuzzy && sortSpec == "" {
+					sortSpec = "-matchScore"
+				}
+				if err := sortArtists(filtered, sortSpec); err != nil {
+					return nil, fmt.Errorf("tri invalide")
+				}
+				start, end, err := pageArgs(args, len(filtered))
+				if err != nil {
+					return nil, err
+				}
+				out := make([]*ArtistWithMeta, 0, end-start)
+				for i := start; i < end; i++ {
+					out = append(out, &filtered[i])
+				}
+				return out, nil
+			},
+		},
+		{
+			Name: "artist", Type: artistT, Description: "One artist by ID or slug.",
+			Args: []*gqlArg{intArg("id", "Artist ID."), stringArg("slug", "Artist slug, e.g. queen.")},
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				key, _ := args["slug"].(string)
+				if id, ok := args["id"].(int); ok {
+					key = strconv.Itoa(id)
+				}
+				if key == "" {
+					return nil, fmt.Errorf("id ou slug est requis")
+				}
+				art, ok := findArtist(ec.data().artists, key)
+				if !ok {
+					return nil, nil
+				}
+				return ec.data().artistByID[art.ID], nil
+			},
+		},
+		{
+			Name: "members", Type: nonNull(listOf(nonNull(memberT))),
+			Args: []*gqlArg{
+				stringArg("name", "Member name contains this text."),
+				intArg("minBands", "Minimum number of bands."),
+			},
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				name, _ := args["name"].(string)
+				name = normalizeMemberName(name)
+				minBands, _ := args["minBands"].(int)
+				members := ec.data().members
+				out := make([]*Member, 0, len(members))
+				for i := range members {
+					if name != "" && !strings.Contains(members[i].NormalizedName, name) {
+						continue
+					}
+					if len(members[i].Bands) < minBands {
+						continue
+					}
+					out = append(out, &members[i])
+				}
+				return out, nil
+			},
+		},
+		{
+			Name: "member", Type: memberT,
+			Args: []*gqlArg{{Name: "id", Description: "Member ID.", Type: nonNull(gqlIDT)}},
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				members := ec.data().members
+				for i := range members {
+					if members[i].ID == args["id"].(string) {
+						return &members[i], nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name: "locations", Type: nonNull(listOf(nonNull(locationT))),
+			Args: []*gqlArg{
+				stringArg("country", "Country contains this text."),
+				stringArg("city", "City contains this text."),
+				stringArg("artist", "Artist name contains this text."),
+			},
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				country, _ := args["country"].(string)
+				city, _ := args["city"].(string)
+				artist, _ := args["artist"].(string)
+				out := make([]viewLocation, 0)
+				for _, loc := range ec.data().locations {
+					if textContains(loc.Country, country) && textContains(loc.City, city) && textContains(loc.ArtistName, artist) {
+						out = append(out, loc)
+					}
+				}
+				return out, nil
+			},
+		},
+		{
+			Name: "events", Type: nonNull(listOf(nonNull(eventT))),
+			Description: "Concerts matching every filter, with the same semantics as /api/events.",
+			Args: append([]*gqlArg{
+				stringArg("country", "Country contains this text."),
+				stringArg("city", "City contains this text."),
+				stringArg("artist", "Artist name contains this text."),
+				intArg("year", "Concert year."),
+				stringArg("dateFrom", "First day included (YYYY-MM-DD)."),
+				stringArg("dateTo", "Last day included (YYYY-MM-DD)."),
+				{Name: "when", Type: windowT},
+				stringArg("tz", "IANA time zone used for TODAY (default UTC)."),
+			}, pageGQLArgs()...),
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				q := argValues(args, eventFilterArgs)
+				q.Set("when", strings.ToLower(q.Get("when")))
+				filters, err := parseEventFilters(q, ec.app.currentTime())
+				if err != nil {
+					return nil, err
+				}
+				filtered := filterEvents(ec.data().events, filters)
+				start, end, err := pageArgs(args, len(filtered))
+				if err != nil {
+					return nil, err
+				}
+				return filtered[start:end], nil
+			},
+		},
+		{
+			Name: "spotifyArtist", Type: spotifyT, Description: "Live lookup on Spotify; needs the Spotify integration.",
+			Args: []*gqlArg{{Name: "id", Description: "Spotify artist ID.", Type: nonNull(gqlIDT)}},
+			Resolve: func(ec *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if ec.app.spotify == nil {
+					return nil, errors.New(problemMessage(codeSpotifyDisabled))
+				}
+				ctx, cancel := context.WithTimeout(ec.ctx, 8*time.Second)
+				defer cancel()
+				artist, err := ec.app.spotify.GetArtist(ctx, args["id"].(string))
+				if err != nil {
+					log.Printf("spotify artist lookup failed: %v", err)
+					if code := mapSpotifyError(err); code != codeArtistNotFound {
+						return nil, errors.New(problemMessage(code))
+					}
+					return nil, nil
+				}
+				ec.app.linkSpotifyMatches([]SpotifyArtist{artist})
+				return spotifyArtistDetail{
+					ID:         artist.ID,
+					Name:       artist.Name,
+					ImageURL:   pickBestImage(artist.Images),
+					Genres:     artist.Genres,
+					Popularity: artist.Popularity,
+					Followers:  artist.Followers.Total,
+					Source:     sourceSpotify,
+				}, nil
+			},
+		},
+	}
+	return newSchema(query, gqlSkipInclude)
+}
+
+var (
+	graphQLSchemaOnce sync.Once
+	graphQLSchema     *gqlSchema
+)
+
+// graphQLRequest is the JSON body of a POST request.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// handleGraphQL serves GraphQL over HTTP: GET with query, operationName and
+// variables parameters, or POST with a JSON body (or application/graphql).
+func (a *App) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		methodNotAllowed(w, r)
+		return
+	}
+	graphQLSchemaOnce.Do(func() { graphQLSchema = newGraphQLSchema() })
+
+	var req graphQLRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if raw := q.Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				writeJSON(w, http.StatusBadRequest, gqlResponse{Errors: []*gqlError{{Message: "variables doit être un objet JSON"}}})
+				return
+			}
+		}
+	} else {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxGraphQLBody+1))
+		if err != nil || len(body) > maxGraphQLBody {
+			writeJSON(w, http.StatusRequestEntityTooLarge, gqlResponse{Errors: []*gqlError{{Message: "requête GraphQL trop volumineuse"}}})
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/graphql" {
+			req.Query = string(body)
+		} else if err := json.Unmarshal(body, &req); err != nil {
+			writeJSON(w, http.StatusBadRequest, gqlResponse{Errors: []*gqlError{{Message: "corps JSON invalide"}}})
+			return
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeJSON(w, http.StatusBadRequest, gqlResponse{Errors: []*gqlError{{Message: "le paramètre query est requis"}}})
+		return
+	}
+
+	result, executed := a.executeGraphQL(r.Context(), graphQLSchema, req.Query, req.OperationName, req.Variables)
+	if !executed {
+		writeJSON(w, http.StatusBadRequest, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,731 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Limits applied to every GraphQL request.
+const (
+	// gqlMaxDepth bounds field nesting. Introspection fields are not counted:
+	// the standard introspection query nests ofType well past any sane limit.
+	gqlMaxDepth = 6
+	// gqlMaxFields bounds the number of fields resolved by one request, so a
+	// shallow but wide query cannot blow up either.
+	gqlMaxFields = 100000
+	// gqlMaxSelections bounds the selections validated for one request,
+	// counting each fragment once per type and depth it is spread at.
+	gqlMaxSelections = 2000
+)
+
+// gqlEnumLiteral is an enum value written in the query, as opposed to a string.
+type gqlEnumLiteral string
+
+// gqlObjectResult keeps response keys in selection order.
+type gqlObjectResult struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *gqlObjectResult) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *gqlObjectResult) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// gqlExec is the state of one request execution.
+type gqlExec struct {
+	ctx       context.Context
+	app       *App
+	schema    *gqlSchema
+	doc       *gqlDocument
+	vars      map[string]interface{}
+	errors    []*gqlError
+	resolved  int
+	exhausted bool
+	store     *gqlData
+}
+
+func (ec *gqlExec) addError(err *gqlError) {
+	ec.errors = append(ec.errors, err)
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	out := make([]interface{}, len(path)+1)
+	copy(out, path)
+	out[len(path)] = key
+	return out
+}
+
+// selectOperation picks the operation to run. Errors are *gqlError.
+func selectOperation(doc *gqlDocument, name string) (*gqlOperation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, &gqlError{Message: "operationName est requis quand le document contient plusieurs opérations"}
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, &gqlError{Message: fmt.Sprintf("opération %q introuvable", name)}
+}
+
+// validate checks the operation against the schema before anything runs:
+// unknown fields and arguments, leaf/object selections, fragments, variables
+// and the depth limit.
+func (ec *gqlExec) validate(op *gqlOperation) []*gqlError {
+	var errs []*gqlError
+	if op.Type != "query" {
+		return []*gqlError{gqlErrorf(op.Loc, "les opérations %s ne sont pas prises en charge", op.Type)}
+	}
+	declared := make(map[string]bool, len(op.Vars))
+	for _, def := range op.Vars {
+		if declared[def.Name] {
+			errs = append(errs, gqlErrorf(def.Loc, "la variable $%s est déclarée plusieurs fois", def.Name))
+		}
+		declared[def.Name] = true
+		if t := ec.schema.resolveTypeRef(def.Type); t == nil || !t.IsLeaf() {
+			errs = append(errs, gqlErrorf(def.Loc, "type %s invalide pour la variable $%s", def.Type, def.Name))
+		}
+	}
+
+	var checkValue func(v gqlValue)
+	checkValue = func(v gqlValue) {
+		switch v.Kind {
+		case valVariable:
+			if !declared[v.Raw] {
+				errs = append(errs, gqlErrorf(v.Loc, "la variable $%s n'est pas déclarée", v.Raw))
+			}
+		case valList:
+			for _, item := range v.List {
+				checkValue(item)
+			}
+		case valObject:
+			for _, f := range v.Fields {
+				checkValue(f.Value)
+			}
+		}
+	}
+	checkArgs := func(defs []*gqlArg, args []gqlArgument, loc gqlLoc, owner string) {
+		seen := make(map[string]bool, len(args))
+		for _, arg := range args {
+			var def *gqlArg
+			for _, d := range defs {
+				if d.Name == arg.Name {
+					def = d
+				}
+			}
+			if def == nil {
+				errs = append(errs, gqlErrorf(arg.Loc, "argument « %s » inconnu sur %s", arg.Name, owner))
+				continue
+			}
+			if seen[arg.Name] {
+				errs = append(errs, gqlErrorf(arg.Loc, "argument « %s » répété", arg.Name))
+			}
+			seen[arg.Name] = true
+			checkValue(arg.Value)
+			if !containsVariable(arg.Value) {
+				if _, err := ec.coerceLiteral(def.Type, arg.Value); err != nil {
+					errs = append(errs, gqlErrorf(arg.Loc, "argument « %s » de %s : %s", arg.Name, owner, err.Error()))
+				}
+			}
+		}
+		for _, d := range defs {
+			if d.Type.Kind == gqlKindNonNull && d.Default == nil && !seen[d.Name] {
+				errs = append(errs, gqlErrorf(loc, "l'argument obligatoire « %s » de %s est manquant", d.Name, owner))
+			}
+		}
+	}
+	checkDirectives := func(dirs []gqlDirective) {
+		for _, d := range dirs {
+			var def *gqlDirectiveDef
+			for _, known := range ec.schema.Directives {
+				if known.Name == d.Name {
+					def = known
+				}
+			}
+			if def == nil {
+				errs = append(errs, gqlErrorf(d.Loc, "directive @%s inconnue", d.Name))
+				continue
+			}
+			checkArgs(def.Args, d.Args, d.Loc, "@"+d.Name)
+		}
+	}
+
+	depthReported := false
+	// walked remembers the fragments already validated: spreading one many
+	// times, directly or through other fragments, must not validate it again,
+	// or chained fragments that each spread the next twice cost 2^n.
+	type fragmentUse struct {
+		name, typ     string
+		depth         int
+		introspection bool
+	}
+	walked := make(map[fragmentUse]bool)
+	selections := 0
+	var walk func(t *gqlType, sels []gqlSelection, depth int, introspection bool, fragments map[string]bool)
+	walk = func(t *gqlType, sels []gqlSelection, depth int, introspection bool, fragments map[string]bool) {
+		for _, sel := range sels {
+			if selections++; selections > gqlMaxSelections {
+				if selections == gqlMaxSelections+1 {
+					errs = append(errs, gqlErrorf(sel.Loc, "la requête dépasse %d sélections", gqlMaxSelections))
+				}
+				return
+			}
+			checkDirectives(sel.Directives)
+			switch {
+			case sel.Spread != "":
+				frag, ok := ec.doc.Fragments[sel.Spread]
+				if !ok {
+					errs = append(errs, gqlErrorf(sel.Loc, "fragment « %s » inconnu", sel.Spread))
+					continue
+				}
+				if fragments[sel.Spread] {
+					errs = append(errs, gqlErrorf(sel.Loc, "le fragment « %s » s'inclut lui-même", sel.Spread))
+					continue
+				}
+				if frag.TypeCondition != t.Name {
+					errs = append(errs, gqlErrorf(sel.Loc, "le fragment « %s » sur %s ne peut pas être utilisé sur %s", sel.Spread, frag.TypeCondition, t.Name))
+					continue
+				}
+				use := fragmentUse{sel.Spread, t.Name, depth, introspection}
+				if walked[use] {
+					continue
+				}
+				walked[use] = true
+				checkDirectives(frag.Directives)
+				fragments[sel.Spread] = true
+				walk(t, frag.Selections, depth, introspection, fragments)
+				delete(fragments, sel.Spread)
+			case sel.Inline:
+				if sel.TypeCondition != "" && sel.TypeCondition != t.Name {
+					errs = append(errs, gqlErrorf(sel.Loc, "un fragment sur %s ne peut pas être utilisé sur %s", sel.TypeCondition, t.Name))
+					continue
+				}
+				walk(t, sel.Selections, depth, introspection, fragments)
+			default:
+				if sel.Name == gqlTypenameName {
+					if len(sel.Selections) > 0 {
+						errs = append(errs, gqlErrorf(sel.Loc, "le champ « __typename » n'accepte pas de sous-sélection"))
+					}
+					continue
+				}
+				field := ec.schema.lookupField(t, sel.Name)
+				if field == nil {
+					errs = append(errs, gqlErrorf(sel.Loc, "le champ « %s » n'existe pas sur le type %s", sel.Name, t.Name))
+					continue
+				}
+				checkArgs(field.Args, sel.Args, sel.Loc, fmt.Sprintf("%s.%s", t.Name, sel.Name))
+				inner := introspection || (len(sel.Name) > 1 && sel.Name[:2] == "__")
+				next := depth
+				if !inner {
+					next++
+					if next > gqlMaxDepth && !depthReported {
+						depthReported = true
+						errs = append(errs, gqlErrorf(sel.Loc, "la requête dépasse la profondeur maximale de %d", gqlMaxDepth))
+					}
+				}
+				if field.Type.IsLeaf() {
+					if len(sel.Selections) > 0 {
+						errs = append(errs, gqlErrorf(sel.Loc, "le champ « %s » de type %s n'accepte pas de sous-sélection", sel.Name, field.Type))
+					}
+					continue
+				}
+				if len(sel.Selections) == 0 {
+					errs = append(errs, gqlErrorf(sel.Loc, "le champ « %s » de type %s exige une sous-sélection", sel.Name, field.Type))
+					continue
+				}
+				walk(field.Type.Named(), sel.Selections, next, inner, fragments)
+			}
+		}
+	}
+	checkDirectives(op.Directives)
+	walk(ec.schema.Query, op.Selections, 0, false, make(map[string]bool))
+	return errs
+}
+
+func containsVariable(v gqlValue) bool {
+	switch v.Kind {
+	case valVariable:
+		return true
+	case valList:
+		for _, item := range v.List {
+			if containsVariable(item) {
+				return true
+			}
+		}
+	case valObject:
+		for _, f := range v.Fields {
+			if containsVariable(f.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coerceVariables validates the request variables against the operation's
+// declarations and applies defaults.
+func (ec *gqlExec) coerceVariables(op *gqlOperation, raw map[string]interface{}) []*gqlError {
+	var errs []*gqlError
+	ec.vars = make(map[string]interface{}, len(op.Vars))
+	for _, def := range op.Vars {
+		t := ec.schema.resolveTypeRef(def.Type)
+		value, provided := raw[def.Name]
+		if !provided {
+			if def.Default != nil {
+				v, err := ec.coerceLiteral(t, *def.Default)
+				if err != nil {
+					errs = append(errs, gqlErrorf(def.Loc, "valeur par défaut de $%s : %s", def.Name, err.Error()))
+					continue
+				}
+				ec.vars[def.Name] = v
+			} else if t.Kind == gqlKindNonNull {
+				errs = append(errs, gqlErrorf(def.Loc, "la variable $%s de type %s est obligatoire", def.Name, def.Type))
+			}
+			continue
+		}
+		v, err := coerceInput(t, value, false)
+		if err != nil {
+			errs = append(errs, gqlErrorf(def.Loc, "variable $%s : %s", def.Name, err.Error()))
+			continue
+		}
+		ec.vars[def.Name] = v
+	}
+	return errs
+}
+
+// coerceLiteral converts a literal from the query to the Go value of t.
+func (ec *gqlExec) coerceLiteral(t *gqlType, v gqlValue) (interface{}, error) {
+	raw, err := ec.literalValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return coerceInput(t, raw, true)
+}
+
+func (ec *gqlExec) literalValue(v gqlValue) (interface{}, error) {
+	switch v.Kind {
+	case valVariable:
+		return ec.vars[v.Raw], nil
+	case valInt:
+		n, err := strconv.Atoi(v.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("entier %s hors limites", v.Raw)
+		}
+		return n, nil
+	case valFloat:
+		return strconv.ParseFloat(v.Raw, 64)
+	case valString:
+		return v.Raw, nil
+	case valBoolean:
+		return v.Raw == "true", nil
+	case valNull:
+		return nil, nil
+	case valEnum:
+		return gqlEnumLiteral(v.Raw), nil
+	case valList:
+		out := make([]interface{}, 0, len(v.List))
+		for _, item := range v.List {
+			x, err := ec.literalValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, x)
+		}
+		return out, nil
+	}
+	out := make(map[string]interface{}, len(v.Fields))
+	for _, f := range v.Fields {
+		x, err := ec.literalValue(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		out[f.Name] = x
+	}
+	return out, nil
+}
+
+// coerceInput converts a literal (literal=true) or JSON variable value to the
+// Go representation of t: int, float64, string, bool or []interface{}.
+func coerceInput(t *gqlType, v interface{}, literal bool) (interface{}, error) {
+	if t.Kind == gqlKindNonNull {
+		if v == nil {
+			return nil, fmt.Errorf("valeur %s attendue, null reçu", t)
+		}
+		return coerceInput(t.OfType, v, literal)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	if t.Kind == gqlKindList {
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			x, err := coerceInput(t.OfType, item, literal)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, x)
+		}
+		return out, nil
+	}
+	invalid := fmt.Errorf("valeur %s attendue, reçu %s", t.Name, formatInput(v))
+	switch t.Kind {
+	case gqlKindEnum:
+		name, ok := v.(gqlEnumLiteral)
+		if !ok && !literal {
+			var s string
+			s, ok = v.(string)
+			name = gqlEnumLiteral(s)
+		}
+		if !ok || !t.hasEnumValue(string(name)) {
+			return nil, invalid
+		}
+		return string(name), nil
+	case gqlKindScalar:
+		switch t.Name {
+		case "Int":
+			switch n := v.(type) {
+			case int:
+				if n >= math.MinInt32 && n <= math.MaxInt32 {
+					return n, nil
+				}
+			case float64:
+				if !literal && n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+					return int(n), nil
+				}
+			}
+		case "Float":
+			switch n := v.(type) {
+			case int:
+				return float64(n), nil
+			case float64:
+				return n, nil
+			}
+		case "String":
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+		case "Boolean":
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+		case "ID":
+			switch id := v.(type) {
+			case string:
+				return id, nil
+			case int:
+				return strconv.Itoa(id), nil
+			case float64:
+				if !literal && id == math.Trunc(id) {
+					return strconv.FormatFloat(id, 'f', 0, 64), nil
+				}
+			}
+		}
+	}
+	return nil, invalid
+}
+
+func formatInput(v interface{}) string {
+	if s := formatLiteral(v); s != "" {
+		return s
+	}
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// fieldArgs coerces the arguments of one field occurrence. Variables that
+// were not provided count as absent, so argument defaults apply.
+func (ec *gqlExec) fieldArgs(defs []*gqlArg, args []gqlArgument) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		var provided *gqlArgument
+		for i := range args {
+			if args[i].Name == def.Name {
+				provided = &args[i]
+			}
+		}
+		if provided != nil && provided.Value.Kind == valVariable {
+			if _, ok := ec.vars[provided.Value.Raw]; !ok {
+				provided = nil
+			}
+		}
+		if provided == nil {
+			if def.Default != nil {
+				out[def.Name] = def.Default
+			} else if def.Type.Kind == gqlKindNonNull {
+				return nil, fmt.Errorf("l'argument obligatoire « %s » est manquant", def.Name)
+			}
+			continue
+		}
+		v, err := ec.coerceLiteral(def.Type, provided.Value)
+		if err != nil {
+			return nil, fmt.Errorf("argument « %s » : %s", def.Name, err.Error())
+		}
+		out[def.Name] = v
+	}
+	return out, nil
+}
+
+// included evaluates @skip and @include.
+func (ec *gqlExec) included(dirs []gqlDirective) bool {
+	for _, d := range dirs {
+		if d.Name != "skip" && d.Name != "include" {
+			continue
+		}
+		for _, def := range ec.schema.Directives {
+			if def.Name != d.Name {
+				continue
+			}
+			args, err := ec.fieldArgs(def.Args, d.Args)
+			if err != nil {
+				continue
+			}
+			cond, _ := args["if"].(bool)
+			if (d.Name == "skip") == cond {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// gqlCollected groups every occurrence of one response key.
+type gqlCollected struct {
+	key   string
+	nodes []gqlSelection
+}
+
+// collectFields flattens fragments and merges fields sharing a response key.
+func (ec *gqlExec) collectFields(sels []gqlSelection, out []*gqlCollected, visited map[string]bool) []*gqlCollected {
+	for _, sel := range sels {
+		if !ec.included(sel.Directives) {
+			continue
+		}
+		switch {
+		case sel.Spread != "":
+			if visited[sel.Spread] {
+				continue
+			}
+			visited[sel.Spread] = true
+			frag := ec.doc.Fragments[sel.Spread]
+			if ec.included(frag.Directives) {
+				out = ec.collectFields(frag.Selections, out, visited)
+			}
+		case sel.Inline:
+			out = ec.collectFields(sel.Selections, out, visited)
+		default:
+			key := sel.ResponseKey()
+			found := false
+			for _, c := range out {
+				if c.key == key {
+					c.nodes = append(c.nodes, sel)
+					found = true
+					break
+				}
+			}
+			if !found {
+				out = append(out, &gqlCollected{key: key, nodes: []gqlSelection{sel}})
+			}
+		}
+	}
+	return out
+}
+
+// executeSelectionSet resolves the fields of an object. ok is false when a
+// non-null field failed, which makes the whole object null.
+func (ec *gqlExec) executeSelectionSet(t *gqlType, source interface{}, sels []gqlSelection, path []interface{}) (*gqlObjectResult, bool) {
+	result := &gqlObjectResult{values: make(map[string]interface{})}
+	for _, c := range ec.collectFields(sels, nil, make(map[string]bool)) {
+		if ec.exhausted {
+			return nil, false
+		}
+		node := c.nodes[0]
+		if node.Name == gqlTypenameName {
+			result.set(c.key, t.Name)
+			continue
+		}
+		ec.resolved++
+		if ec.resolved > gqlMaxFields {
+			ec.exhausted = true
+			ec.addError(gqlErrorf(node.Loc, "la réponse dépasse %d champs, affinez la requête", gqlMaxFields))
+			return nil, false
+		}
+		field := ec.schema.lookupField(t, node.Name)
+		fieldPath := appendPath(path, c.key)
+		value, ok := ec.executeField(field, source, c.nodes, fieldPath)
+		if !ok {
+			return nil, false
+		}
+		result.set(c.key, value)
+	}
+	return result, true
+}
+
+func (ec *gqlExec) executeField(field *gqlField, source interface{}, nodes []gqlSelection, path []interface{}) (interface{}, bool) {
+	node := nodes[0]
+	fail := func(err error) (interface{}, bool) {
+		ec.addError(&gqlError{Message: err.Error(), Locations: []gqlLoc{node.Loc}, Path: path})
+		return nil, field.Type.Kind != gqlKindNonNull
+	}
+	args, err := ec.fieldArgs(field.Args, node.Args)
+	if err != nil {
+		return fail(err)
+	}
+	value, err := field.Resolve(ec, source, args)
+	if err != nil {
+		return fail(err)
+	}
+	var sub []gqlSelection
+	for _, n := range nodes {
+		sub = append(sub, n.Selections...)
+	}
+	return ec.completeValue(field.Type, sub, node.Loc, value, path)
+}
+
+// completeValue serializes a resolved value. A null in a non-null position
+// returns ok=false so that the nearest nullable parent becomes null.
+func (ec *gqlExec) completeValue(t *gqlType, sels []gqlSelection, loc gqlLoc, value interface{}, path []interface{}) (interface{}, bool) {
+	if t.Kind == gqlKindNonNull {
+		v, ok := ec.completeInner(t.OfType, sels, loc, value, path)
+		if ok && v == nil {
+			ec.addError(&gqlError{Message: fmt.Sprintf("valeur null impossible pour le type %s", t), Locations: []gqlLoc{loc}, Path: path})
+		}
+		return v, ok && v != nil
+	}
+	v, ok := ec.completeInner(t, sels, loc, value, path)
+	if !ok {
+		return nil, true
+	}
+	return v, true
+}
+
+func (ec *gqlExec) completeInner(t *gqlType, sels []gqlSelection, loc gqlLoc, value interface{}, path []interface{}) (interface{}, bool) {
+	if isNil(value) {
+		return nil, true
+	}
+	switch t.Kind {
+	case gqlKindList:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			ec.addError(&gqlError{Message: fmt.Sprintf("liste attendue pour le type %s", t), Locations: []gqlLoc{loc}, Path: path})
+			return nil, false
+		}
+		out := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, ok := ec.completeValue(t.OfType, sels, loc, rv.Index(i).Interface(), appendPath(path, i))
+			if !ok {
+				return nil, false
+			}
+			out = append(out, item)
+		}
+		return out, true
+	case gqlKindObject:
+		obj, ok := ec.executeSelectionSet(t, value, sels, path)
+		if !ok {
+			return nil, false
+		}
+		return obj, true
+	}
+	return serializeLeaf(t, value), true
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// serializeLeaf converts a resolved scalar or enum to its JSON form.
+func serializeLeaf(t *gqlType, v interface{}) interface{} {
+	if t.Name == "ID" {
+		if n, ok := v.(int); ok {
+			return strconv.Itoa(n)
+		}
+	}
+	return v
+}
+
+// gqlResponse is the body of a request that failed before execution: it has
+// errors but no "data" key.
+type gqlResponse struct {
+	Errors []*gqlError `json:"errors"`
+}
+
+// gqlExecuted is the body of an executed request; data may be null.
+type gqlExecuted struct {
+	Errors []*gqlError  `json:"errors,omitempty"`
+	Data   *interface{} `json:"data"`
+}
+
+// executeGraphQL parses, validates and runs a request. The boolean reports
+// whether execution started, in which case the response carries "data".
+func (a *App) executeGraphQL(ctx context.Context, schema *gqlSchema, query, operationName string, variables map[string]interface{}) (interface{}, bool) {
+	doc, err := parseGraphQL(query)
+	if err != nil {
+		return gqlResponse{Errors: []*gqlError{toGQLError(err)}}, false
+	}
+	op, err := selectOperation(doc, operationName)
+	if err != nil {
+		return gqlResponse{Errors: []*gqlError{toGQLError(err)}}, false
+	}
+	ec := &gqlExec{ctx: ctx, app: a, schema: schema, doc: doc}
+	if errs := ec.validate(op); len(errs) > 0 {
+		return gqlResponse{Errors: errs}, false
+	}
+	if errs := ec.coerceVariables(op, variables); len(errs) > 0 {
+		return gqlResponse{Errors: errs}, false
+	}
+
+	var data interface{}
+	if obj, ok := ec.executeSelectionSet(schema.Query, nil, op.Selections, nil); ok {
+		data = obj
+	}
+	return gqlExecuted{Errors: ec.errors, Data: &data}, true
+}
+
+func toGQLError(err error) *gqlError {
+	if ge, ok := err.(*gqlError); ok {
+		return ge
+	}
+	return &gqlError{Message: err.Error()}
+}
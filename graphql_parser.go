@@ -0,0 +1,801 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file parses the executable subset of GraphQL documents: operations,
+// variables, fields, aliases, arguments, fragments and directives. The type
+// system definition language is not supported since the schema lives in Go.
+
+// gqlLoc is a 1-based line and column in the query text.
+type gqlLoc struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// gqlError is a GraphQL error as reported in the "errors" list.
+type gqlError struct {
+	Message   string        `json:"message"`
+	Locations []gqlLoc      `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *gqlError) Error() string {
+	return e.Message
+}
+
+func gqlErrorf(loc gqlLoc, format string, args ...interface{}) *gqlError {
+	return &gqlError{Message: fmt.Sprintf(format, args...), Locations: []gqlLoc{loc}}
+}
+
+// Value kinds of gqlValue.
+const (
+	valVariable = iota
+	valInt
+	valFloat
+	valString
+	valBoolean
+	valNull
+	valEnum
+	valList
+	valObject
+)
+
+// gqlValue is an argument or default value literal.
+type gqlValue struct {
+	Kind   int
+	Raw    string // variable name, number, string, boolean or enum name
+	List   []gqlValue
+	Fields []gqlObjectField
+	Loc    gqlLoc
+}
+
+type gqlObjectField struct {
+	Name  string
+	Value gqlValue
+}
+
+// gqlTypeRef is a type in a variable definition, e.g. [String!]!.
+type gqlTypeRef struct {
+	Name    string
+	List    *gqlTypeRef
+	NonNull bool
+}
+
+func (t gqlTypeRef) String() string {
+	s := t.Name
+	if t.List != nil {
+		s = "[" + t.List.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+type gqlVarDef struct {
+	Name    string
+	Type    gqlTypeRef
+	Default *gqlValue
+	Loc     gqlLoc
+}
+
+type gqlArgument struct {
+	Name  string
+	Value gqlValue
+	Loc   gqlLoc
+}
+
+type gqlDirective struct {
+	Name string
+	Args []gqlArgument
+	Loc  gqlLoc
+}
+
+// gqlSelection is a field, a fragment spread (Spread set) or an inline
+// fragment (Inline set, TypeCondition optional).
+type gqlSelection struct {
+	Alias      string
+	Name       string
+	Args       []gqlArgument
+	Selections []gqlSelection
+
+	Spread        string
+	Inline        bool
+	TypeCondition string
+
+	Directives []gqlDirective
+	Loc        gqlLoc
+}
+
+// ResponseKey is the alias when set, the field name otherwise.
+func (s gqlSelection) ResponseKey() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Name
+}
+
+type gqlOperation struct {
+	Type       string // query, mutation or subscription
+	Name       string
+	Vars       []gqlVarDef
+	Directives []gqlDirective
+	Selections []gqlSelection
+	Loc        gqlLoc
+}
+
+type gqlFragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []gqlDirective
+	Selections    []gqlSelection
+	Loc           gqlLoc
+}
+
+type gqlDocument struct {
+	Operations []*gqlOperation
+	Fragments  map[string]*gqlFragment
+}
+
+// Token kinds.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type gqlToken struct {
+	Kind  int
+	Value string
+	Loc   gqlLoc
+}
+
+// gqlLexer splits a document into tokens, skipping whitespace, commas and comments.
+type gqlLexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+}
+
+func (l *gqlLexer) loc() gqlLoc {
+	return gqlLoc{Line: l.line, Column: l.col}
+}
+
+func (l *gqlLexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *gqlLexer) peekByte(n int) byte {
+	if l.off+n >= len(l.src) {
+		return 0
+	}
+	return l.src[l.off+n]
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *gqlLexer) next() (gqlToken, error) {
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.advance()
+			continue
+		}
+		if c == '#' {
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.advance()
+			}
+			continue
+		}
+		break
+	}
+	start := l.loc()
+	if l.off >= len(l.src) {
+		return gqlToken{Kind: tokEOF, Loc: start}, nil
+	}
+	c := l.src[l.off]
+	switch {
+	case strings.IndexByte("!$()...:=@[]{}|", c) >= 0:
+		if c == '.' {
+			if !strings.HasPrefix(l.src[l.off:], "...") {
+				return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : « . » inattendu")
+			}
+			l.advance()
+			l.advance()
+			l.advance()
+			return gqlToken{Kind: tokPunct, Value: "...", Loc: start}, nil
+		}
+		l.advance()
+		return gqlToken{Kind: tokPunct, Value: string(c), Loc: start}, nil
+	case isNameStart(c):
+		from := l.off
+		for l.off < len(l.src) && isNameChar(l.src[l.off]) {
+			l.advance()
+		}
+		return gqlToken{Kind: tokName, Value: l.src[from:l.off], Loc: start}, nil
+	case c == '-' || isDigit(c):
+		return l.number(start)
+	case c == '"':
+		return l.string(start)
+	}
+	return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : caractère %q inattendu", c)
+}
+
+func (l *gqlLexer) number(start gqlLoc) (gqlToken, error) {
+	from := l.off
+	kind := tokInt
+	if l.src[l.off] == '-' {
+		l.advance()
+	}
+	digits := func() bool {
+		n := 0
+		for l.off < len(l.src) && isDigit(l.src[l.off]) {
+			l.advance()
+			n++
+		}
+		return n > 0
+	}
+	if !digits() {
+		return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : nombre invalide")
+	}
+	if l.peekByte(0) == '.' {
+		kind = tokFloat
+		l.advance()
+		if !digits() {
+			return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : nombre invalide")
+		}
+	}
+	if c := l.peekByte(0); c == 'e' || c == 'E' {
+		kind = tokFloat
+		l.advance()
+		if c := l.peekByte(0); c == '+' || c == '-' {
+			l.advance()
+		}
+		if !digits() {
+			return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : nombre invalide")
+		}
+	}
+	if l.off < len(l.src) && (isNameStart(l.src[l.off]) || l.src[l.off] == '.') {
+		return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : nombre invalide")
+	}
+	return gqlToken{Kind: kind, Value: l.src[from:l.off], Loc: start}, nil
+}
+
+func (l *gqlLexer) string(start gqlLoc) (gqlToken, error) {
+	if strings.HasPrefix(l.src[l.off:], `"""`) {
+		return l.blockString(start)
+	}
+	l.advance()
+	var b strings.Builder
+	for l.off < len(l.src) {
+		r := l.advance()
+		switch r {
+		case '"':
+			return gqlToken{Kind: tokString, Value: b.String(), Loc: start}, nil
+		case '\n':
+			return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : chaîne non fermée")
+		case '\\':
+			if l.off >= len(l.src) {
+				return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : chaîne non fermée")
+			}
+			esc := l.advance()
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteRune(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.off+4 > len(l.src) {
+					return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : séquence unicode invalide")
+				}
+				code, err := strconv.ParseUint(l.src[l.off:l.off+4], 16, 32)
+				if err != nil {
+					return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : séquence unicode invalide")
+				}
+				for i := 0; i < 4; i++ {
+					l.advance()
+				}
+				b.WriteRune(rune(code))
+			default:
+				return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : échappement \\%c invalide", esc)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : chaîne non fermée")
+}
+
+// blockString reads a """ string and removes its common indentation.
+func (l *gqlLexer) blockString(start gqlLoc) (gqlToken, error) {
+	for i := 0; i < 3; i++ {
+		l.advance()
+	}
+	from := l.off
+	for l.off < len(l.src) {
+		if strings.HasPrefix(l.src[l.off:], `\"""`) {
+			for i := 0; i < 4; i++ {
+				l.advance()
+			}
+			continue
+		}
+		if strings.HasPrefix(l.src[l.off:], `"""`) {
+			raw := strings.ReplaceAll(l.src[from:l.off], `\"""`, `"""`)
+			for i := 0; i < 3; i++ {
+				l.advance()
+			}
+			return gqlToken{Kind: tokString, Value: dedentBlockString(raw), Loc: start}, nil
+		}
+		l.advance()
+	}
+	return gqlToken{}, gqlErrorf(start, "Erreur de syntaxe : chaîne non fermée")
+}
+
+func dedentBlockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gqlParser is a recursive descent parser with one token of lookahead.
+type gqlParser struct {
+	lex *gqlLexer
+	tok gqlToken
+}
+
+// parseGraphQL parses an executable document. Errors are *gqlError.
+func parseGraphQL(src string) (*gqlDocument, error) {
+	p := &gqlParser{lex: &gqlLexer{src: src, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &gqlDocument{Fragments: make(map[string]*gqlFragment)}
+	for p.tok.Kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			op := &gqlOperation{Type: "query", Loc: p.tok.Loc}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			op.Selections = sels
+			doc.Operations = append(doc.Operations, op)
+		case p.peek(tokName, "query"), p.peek(tokName, "mutation"), p.peek(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peek(tokName, "fragment"):
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.Fragments[frag.Name]; dup {
+				return nil, gqlErrorf(frag.Loc, "le fragment %q est défini plusieurs fois", frag.Name)
+			}
+			doc.Fragments[frag.Name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, &gqlError{Message: "le document ne contient aucune opération"}
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *gqlParser) peek(kind int, value string) bool {
+	return p.tok.Kind == kind && p.tok.Value == value
+}
+
+func (p *gqlParser) unexpected() error {
+	if p.tok.Kind == tokEOF {
+		return gqlErrorf(p.tok.Loc, "Erreur de syntaxe : fin de document inattendue")
+	}
+	return gqlErrorf(p.tok.Loc, "Erreur de syntaxe : %q inattendu", p.tok.Value)
+}
+
+func (p *gqlParser) expect(value string) error {
+	if p.tok.Kind != tokPunct || p.tok.Value != value {
+		if p.tok.Kind == tokEOF {
+			return gqlErrorf(p.tok.Loc, "Erreur de syntaxe : %q attendu, fin de document trouvée", value)
+		}
+		return gqlErrorf(p.tok.Loc, "Erreur de syntaxe : %q attendu, %q trouvé", value, p.tok.Value)
+	}
+	return p.advance()
+}
+
+func (p *gqlParser) name() (string, error) {
+	if p.tok.Kind != tokName {
+		return "", p.unexpected()
+	}
+	name := p.tok.Value
+	return name, p.advance()
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	op := &gqlOperation{Type: p.tok.Value, Loc: p.tok.Loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.Kind == tokName {
+		op.Name = p.tok.Value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokPunct, "(") {
+		vars, err := p.variableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.Vars = vars
+	}
+	dirs, err := p.directives()
+	if err != nil {
+		return nil, err
+	}
+	op.Directives = dirs
+	if op.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *gqlParser) variableDefinitions() ([]gqlVarDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var defs []gqlVarDef
+	for !p.peek(tokPunct, ")") {
+		def := gqlVarDef{Loc: p.tok.Loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		var err error
+		if def.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if def.Type, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if p.peek(tokPunct, "=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			v, err := p.value(true)
+			if err != nil {
+				return nil, err
+			}
+			def.Default = &v
+		}
+		if _, err := p.directives(); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+func (p *gqlParser) typeRef() (gqlTypeRef, error) {
+	var t gqlTypeRef
+	if p.peek(tokPunct, "[") {
+		if err := p.advance(); err != nil {
+			return t, err
+		}
+		inner, err := p.typeRef()
+		if err != nil {
+			return t, err
+		}
+		if err := p.expect("]"); err != nil {
+			return t, err
+		}
+		t.List = &inner
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return t, err
+		}
+		t.Name = name
+	}
+	if p.peek(tokPunct, "!") {
+		t.NonNull = true
+		return t, p.advance()
+	}
+	return t, nil
+}
+
+func (p *gqlParser) fragment() (*gqlFragment, error) {
+	frag := &gqlFragment{Loc: p.tok.Loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if frag.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.Name == "on" {
+		return nil, gqlErrorf(frag.Loc, "Erreur de syntaxe : nom « on » inattendu")
+	}
+	if !p.peek(tokName, "on") {
+		return nil, gqlErrorf(p.tok.Loc, "Erreur de syntaxe : « on » attendu")
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if frag.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *gqlParser) selectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []gqlSelection
+	for !p.peek(tokPunct, "}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, gqlErrorf(p.tok.Loc, "Erreur de syntaxe : sélection vide")
+	}
+	return sels, p.advance()
+}
+
+func (p *gqlParser) selection() (gqlSelection, error) {
+	sel := gqlSelection{Loc: p.tok.Loc}
+	var err error
+	if p.peek(tokPunct, "...") {
+		if err := p.advance(); err != nil {
+			return sel, err
+		}
+		if p.tok.Kind == tokName && p.tok.Value != "on" {
+			sel.Spread = p.tok.Value
+			if err := p.advance(); err != nil {
+				return sel, err
+			}
+			sel.Directives, err = p.directives()
+			return sel, err
+		}
+		sel.Inline = true
+		if p.peek(tokName, "on") {
+			if err := p.advance(); err != nil {
+				return sel, err
+			}
+			if sel.TypeCondition, err = p.name(); err != nil {
+				return sel, err
+			}
+		}
+		if sel.Directives, err = p.directives(); err != nil {
+			return sel, err
+		}
+		sel.Selections, err = p.selectionSet()
+		return sel, err
+	}
+
+	if sel.Name, err = p.name(); err != nil {
+		return sel, err
+	}
+	if p.peek(tokPunct, ":") {
+		if err := p.advance(); err != nil {
+			return sel, err
+		}
+		sel.Alias = sel.Name
+		if sel.Name, err = p.name(); err != nil {
+			return sel, err
+		}
+	}
+	if p.peek(tokPunct, "(") {
+		if sel.Args, err = p.arguments(false); err != nil {
+			return sel, err
+		}
+	}
+	if sel.Directives, err = p.directives(); err != nil {
+		return sel, err
+	}
+	if p.peek(tokPunct, "{") {
+		sel.Selections, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+func (p *gqlParser) arguments(constant bool) ([]gqlArgument, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []gqlArgument
+	for !p.peek(tokPunct, ")") {
+		arg := gqlArgument{Loc: p.tok.Loc}
+		var err error
+		if arg.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.Value, err = p.value(constant); err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil, gqlErrorf(p.tok.Loc, "Erreur de syntaxe : liste d'arguments vide")
+	}
+	return args, p.advance()
+}
+
+func (p *gqlParser) directives() ([]gqlDirective, error) {
+	var dirs []gqlDirective
+	for p.peek(tokPunct, "@") {
+		dir := gqlDirective{Loc: p.tok.Loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if dir.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if p.peek(tokPunct, "(") {
+			if dir.Args, err = p.arguments(false); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// value parses a literal; constant forbids variables (default values).
+func (p *gqlParser) value(constant bool) (gqlValue, error) {
+	v := gqlValue{Loc: p.tok.Loc, Raw: p.tok.Value}
+	switch p.tok.Kind {
+	case tokPunct:
+		switch p.tok.Value {
+		case "$":
+			if constant {
+				return v, gqlErrorf(v.Loc, "Erreur de syntaxe : variable interdite dans une valeur constante")
+			}
+			if err := p.advance(); err != nil {
+				return v, err
+			}
+			name, err := p.name()
+			v.Kind, v.Raw = valVariable, name
+			return v, err
+		case "[":
+			v.Kind = valList
+			if err := p.advance(); err != nil {
+				return v, err
+			}
+			for !p.peek(tokPunct, "]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return v, err
+				}
+				v.List = append(v.List, item)
+			}
+			return v, p.advance()
+		case "{":
+			v.Kind = valObject
+			if err := p.advance(); err != nil {
+				return v, err
+			}
+			for !p.peek(tokPunct, "}") {
+				name, err := p.name()
+				if err != nil {
+					return v, err
+				}
+				if err := p.expect(":"); err != nil {
+					return v, err
+				}
+				field, err := p.value(constant)
+				if err != nil {
+					return v, err
+				}
+				v.Fields = append(v.Fields, gqlObjectField{Name: name, Value: field})
+			}
+			return v, p.advance()
+		}
+		return v, p.unexpected()
+	case tokInt:
+		v.Kind = valInt
+	case tokFloat:
+		v.Kind = valFloat
+	case tokString:
+		v.Kind = valString
+	case tokName:
+		switch p.tok.Value {
+		case "true", "false":
+			v.Kind = valBoolean
+		case "null":
+			v.Kind = valNull
+		default:
+			v.Kind = valEnum
+		}
+	default:
+		return v, p.unexpected()
+	}
+	return v, p.advance()
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type kinds, as reported by introspection.
+const (
+	gqlKindScalar   = "SCALAR"
+	gqlKindObject   = "OBJECT"
+	gqlKindEnum     = "ENUM"
+	gqlKindList     = "LIST"
+	gqlKindNonNull  = "NON_NULL"
+	gqlKindInput    = "INPUT_OBJECT"
+	gqlKindIface    = "INTERFACE"
+	gqlKindUnion    = "UNION"
+	gqlTypenameName = "__typename"
+)
+
+// gqlResolver computes a field from its parent value and coerced arguments.
+type gqlResolver func(ec *gqlExec, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// gqlType is a named type, or a LIST / NON_NULL wrapper around OfType.
+type gqlType struct {
+	Kind        string
+	Name        string
+	Description string
+	Fields      []*gqlField
+	EnumValues  []gqlEnumValue
+	OfType      *gqlType
+}
+
+type gqlEnumValue struct {
+	Name        string
+	Description string
+}
+
+type gqlField struct {
+	Name        string
+	Description string
+	Args        []*gqlArg
+	Type        *gqlType
+	Resolve     gqlResolver
+}
+
+// gqlArg is a field or directive argument. DefaultLiteral is the default in
+// GraphQL syntax for introspection; Default is the same value coerced.
+type gqlArg struct {
+	Name           string
+	Description    string
+	Type           *gqlType
+	Default        interface{}
+	DefaultLiteral string
+}
+
+type gqlDirectiveDef struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []*gqlArg
+}
+
+// gqlSchema holds the query root, every named type and the directives.
+type gqlSchema struct {
+	Query      *gqlType
+	Types      map[string]*gqlType
+	Directives []*gqlDirectiveDef
+	// meta are the introspection fields only available on the query root.
+	meta map[string]*gqlField
+}
+
+func nonNull(t *gqlType) *gqlType {
+	return &gqlType{Kind: gqlKindNonNull, OfType: t}
+}
+
+func listOf(t *gqlType) *gqlType {
+	return &gqlType{Kind: gqlKindList, OfType: t}
+}
+
+// String renders the type in GraphQL syntax, e.g. [Event!]!.
+func (t *gqlType) String() string {
+	switch t.Kind {
+	case gqlKindNonNull:
+		return t.OfType.String() + "!"
+	case gqlKindList:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// Named strips LIST and NON_NULL wrappers.
+func (t *gqlType) Named() *gqlType {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t
+}
+
+// Field looks up a field by name.
+func (t *gqlType) Field(name string) *gqlField {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// IsLeaf reports whether values of the type are serialized directly.
+func (t *gqlType) IsLeaf() bool {
+	k := t.Named().Kind
+	return k == gqlKindScalar || k == gqlKindEnum
+}
+
+func (t *gqlType) hasEnumValue(name string) bool {
+	for _, v := range t.EnumValues {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in scalars.
+var (
+	gqlStringT  = &gqlType{Kind: gqlKindScalar, Name: "String", Description: "UTF-8 text."}
+	gqlIntT     = &gqlType{Kind: gqlKindScalar, Name: "Int", Description: "Signed 32-bit integer."}
+	gqlFloatT   = &gqlType{Kind: gqlKindScalar, Name: "Float", Description: "Double-precision floating point number."}
+	gqlBooleanT = &gqlType{Kind: gqlKindScalar, Name: "Boolean", Description: "true or false."}
+	gqlIDT      = &gqlType{Kind: gqlKindScalar, Name: "ID", Description: "Unique identifier, serialized as a string."}
+)
+
+// prop builds a resolver reading a value off a typed source.
+func prop[T any](get func(T) interface{}) gqlResolver {
+	return func(_ *gqlExec, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(source.(T)), nil
+	}
+}
+
+// newSchema indexes every type reachable from the query root and the
+// introspection types.
+func newSchema(query *gqlType, directives []*gqlDirectiveDef) *gqlSchema {
+	s := &gqlSchema{Query: query, Types: make(map[string]*gqlType), Directives: directives}
+	s.meta = introspectionFields(s)
+	var visit func(t *gqlType)
+	visit = func(t *gqlType) {
+		t = t.Named()
+		if _, seen := s.Types[t.Name]; seen {
+			return
+		}
+		s.Types[t.Name] = t
+		for _, f := range t.Fields {
+			visit(f.Type)
+			for _, a := range f.Args {
+				visit(a.Type)
+			}
+		}
+	}
+	visit(query)
+	for _, f := range s.meta {
+		visit(f.Type)
+	}
+	for _, d := range directives {
+		for _, a := range d.Args {
+			visit(a.Type)
+		}
+	}
+	for _, t := range []*gqlType{gqlStringT, gqlIntT, gqlFloatT, gqlBooleanT, gqlIDT} {
+		visit(t)
+	}
+	return s
+}
+
+// lookupField finds a field on t, including the introspection fields of the
+// query root.
+func (s *gqlSchema) lookupField(t *gqlType, name string) *gqlField {
+	if t == s.Query {
+		if f, ok := s.meta[name]; ok {
+			return f
+		}
+	}
+	return t.Field(name)
+}
+
+// resolveTypeRef turns a variable type such as [String!] into a schema type.
+func (s *gqlSchema) resolveTypeRef(ref gqlTypeRef) *gqlType {
+	var t *gqlType
+	if ref.List != nil {
+		inner := s.resolveTypeRef(*ref.List)
+		if inner == nil {
+			return nil
+		}
+		t = listOf(inner)
+	} else if t = s.Types[ref.Name]; t == nil {
+		return nil
+	}
+	if ref.NonNull {
+		t = nonNull(t)
+	}
+	return t
+}
+
+// Standard directives.
+var gqlSkipInclude = []*gqlDirectiveDef{
+	{
+		Name:        "include",
+		Description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:        []*gqlArg{{Name: "if", Description: "Included when true.", Type: nonNull(gqlBooleanT)}},
+	},
+	{
+		Name:        "skip",
+		Description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		Args:        []*gqlArg{{Name: "if", Description: "Skipped when true.", Type: nonNull(gqlBooleanT)}},
+	},
+}
+
+// introspectionFields builds the __Schema, __Type, ... types and returns the
+// __schema and __type root fields.
+func introspectionFields(s *gqlSchema) map[string]*gqlField {
+	typeKind := &gqlType{Kind: gqlKindEnum, Name: "__TypeKind", Description: "The kind of a type."}
+	for _, k := range []string{gqlKindScalar, gqlKindObject, gqlKindIface, gqlKindUnion, gqlKindEnum, gqlKindInput, gqlKindList, gqlKindNonNull} {
+		typeKind.EnumValues = append(typeKind.EnumValues, gqlEnumValue{Name: k})
+	}
+	directiveLocation := &gqlType{Kind: gqlKindEnum, Name: "__DirectiveLocation", Description: "Where a directive may be used."}
+	for _, l := range []string{
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD", "INLINE_FRAGMENT", "VARIABLE_DEFINITION",
+		"SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION",
+	} {
+		directiveLocation.EnumValues = append(directiveLocation.EnumValues, gqlEnumValue{Name: l})
+	}
+
+	schemaT := &gqlType{Kind: gqlKindObject, Name: "__Schema", Description: "The schema of this server."}
+	typeT := &gqlType{Kind: gqlKindObject, Name: "__Type", Description: "A type of the schema, or a LIST / NON_NULL wrapper."}
+	fieldT := &gqlType{Kind: gqlKindObject, Name: "__Field", Description: "A field of an object type."}
+	inputT := &gqlType{Kind: gqlKindObject, Name: "__InputValue", Description: "An argument."}
+	enumT := &gqlType{Kind: gqlKindObject, Name: "__EnumValue", Description: "A value of an enum type."}
+	directiveT := &gqlType{Kind: gqlKindObject, Name: "__Directive", Description: "A directive supported by the executor."}
+
+	nullableString := func(v string) interface{} {
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+	includeDeprecated := []*gqlArg{{Name: "includeDeprecated", Type: gqlBooleanT, Default: false, DefaultLiteral: "false"}}
+	notDeprecated := []*gqlField{
+		{Name: "isDeprecated", Type: nonNull(gqlBooleanT), Resolve: func(*gqlExec, interface{}, map[string]interface{}) (interface{}, error) { return false, nil }},
+		{Name: "deprecationReason", Type: gqlStringT, Resolve: func(*gqlExec, interface{}, map[string]interface{}) (interface{}, error) { return nil, nil }},
+	}
+
+	schemaT.Fields = []*gqlField{
+		{Name: "description", Type: gqlStringT, Resolve: prop(func(*gqlSchema) interface{} { return nil })},
+		{Name: "types", Type: nonNull(listOf(nonNull(typeT))), Resolve: prop(func(s *gqlSchema) interface{} {
+			names := make([]string, 0, len(s.Types))
+			for name := range s.Types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			out := make([]*gqlType, 0, len(names))
+			for _, name := range names {
+				out = append(out, s.Types[name])
+			}
+			return out
+		})},
+		{Name: "queryType", Type: nonNull(typeT), Resolve: prop(func(s *gqlSchema) interface{} { return s.Query })},
+		{Name: "mutationType", Type: typeT, Resolve: prop(func(*gqlSchema) interface{} { return nil })},
+		{Name: "subscriptionType", Type: typeT, Resolve: prop(func(*gqlSchema) interface{} { return nil })},
+		{Name: "directives", Type: nonNull(listOf(nonNull(directiveT))), Resolve: prop(func(s *gqlSchema) interface{} { return s.Directives })},
+	}
+
+	typeT.Fields = []*gqlField{
+		{Name: "kind", Type: nonNull(typeKind), Resolve: prop(func(t *gqlType) interface{} { return t.Kind })},
+		{Name: "name", Type: gqlStringT, Resolve: prop(func(t *gqlType) interface{} { return nullableString(t.Name) })},
+		{Name: "description", Type: gqlStringT, Resolve: prop(func(t *gqlType) interface{} { return nullableString(t.Description) })},
+		{Name: "specifiedByURL", Type: gqlStringT, Resolve: prop(func(*gqlType) interface{} { return nil })},
+		{Name: "fields", Type: listOf(nonNull(fieldT)), Args: includeDeprecated, Resolve: prop(func(t *gqlType) interface{} {
+			if t.Kind != gqlKindObject {
+				return nil
+			}
+			return t.Fields
+		})},
+		{Name: "interfaces", Type: listOf(nonNull(typeT)), Resolve: prop(func(t *gqlType) interface{} {
+			if t.Kind != gqlKindObject {
+				return nil
+			}
+			return []*gqlType{}
+		})},
+		{Name: "possibleTypes", Type: listOf(nonNull(typeT)), Resolve: prop(func(*gqlType) interface{} { return nil })},
+		{Name: "enumValues", Type: listOf(nonNull(enumT)), Args: includeDeprecated, Resolve: prop(func(t *gqlType) interface{} {
+			if t.Kind != gqlKindEnum {
+				return nil
+			}
+			return t.EnumValues
+		})},
+		{Name: "inputFields", Type: listOf(nonNull(inputT)), Resolve: prop(func(*gqlType) interface{} { return nil })},
+		{Name: "ofType", Type: typeT, Resolve: prop(func(t *gqlType) interface{} {
+			if t.OfType == nil {
+				return nil
+			}
+			return t.OfType
+		})},
+	}
+
+	fieldT.Fields = append([]*gqlField{
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(f *gqlField) interface{} { return f.Name })},
+		{Name: "description", Type: gqlStringT, Resolve: prop(func(f *gqlField) interface{} { return nullableString(f.Description) })},
+		{Name: "args", Type: nonNull(listOf(nonNull(inputT))), Resolve: prop(func(f *gqlField) interface{} { return append([]*gqlArg{}, f.Args...) })},
+		{Name: "type", Type: nonNull(typeT), Resolve: prop(func(f *gqlField) interface{} { return f.Type })},
+	}, notDeprecated...)
+
+	inputT.Fields = []*gqlField{
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(a *gqlArg) interface{} { return a.Name })},
+		{Name: "description", Type: gqlStringT, Resolve: prop(func(a *gqlArg) interface{} { return nullableString(a.Description) })},
+		{Name: "type", Type: nonNull(typeT), Resolve: prop(func(a *gqlArg) interface{} { return a.Type })},
+		{Name: "defaultValue", Type: gqlStringT, Resolve: prop(func(a *gqlArg) interface{} { return nullableString(a.DefaultLiteral) })},
+	}
+
+	enumT.Fields = append([]*gqlField{
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(v gqlEnumValue) interface{} { return v.Name })},
+		{Name: "description", Type: gqlStringT, Resolve: prop(func(v gqlEnumValue) interface{} { return nullableString(v.Description) })},
+	}, notDeprecated...)
+
+	directiveT.Fields = []*gqlField{
+		{Name: "name", Type: nonNull(gqlStringT), Resolve: prop(func(d *gqlDirectiveDef) interface{} { return d.Name })},
+		{Name: "description", Type: gqlStringT, Resolve: prop(func(d *gqlDirectiveDef) interface{} { return nullableString(d.Description) })},
+		{Name: "locations", Type: nonNull(listOf(nonNull(directiveLocation))), Resolve: prop(func(d *gqlDirectiveDef) interface{} { return d.Locations })},
+		{Name: "args", Type: nonNull(listOf(nonNull(inputT))), Resolve: prop(func(d *gqlDirectiveDef) interface{} { return append([]*gqlArg{}, d.Args...) })},
+		{Name: "isRepeatable", Type: nonNull(gqlBooleanT), Resolve: prop(func(*gqlDirectiveDef) interface{} { return false })},
+	}
+
+	return map[string]*gqlField{
+		"__schema": {
+			Name: "__schema", Description: "Access the current type schema of this server.", Type: nonNull(schemaT),
+			Resolve: func(*gqlExec, interface{}, map[string]interface{}) (interface{}, error) { return s, nil },
+		},
+		"__type": {
+			Name: "__type", Description: "Request the type information of a single type.", Type: typeT,
+			Args: []*gqlArg{{Name: "name", Type: nonNull(gqlStringT)}},
+			Resolve: func(_ *gqlExec, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				if t, ok := s.Types[args["name"].(string)]; ok {
+					return t, nil
+				}
+				return nil, nil
+			},
+		},
+	}
+}
+
+// formatLiteral renders a coerced input value in GraphQL syntax, for defaults.
+func formatLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case gqlEnumLiteral:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatLiteral(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return ""
+}
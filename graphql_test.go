@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func graphQLTestApp() *App {
+	app := newTestApp()
+	app.cache.Set(queenFloydBundle())
+	return app
+}
+
+type graphQLResult struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message   string        `json:"message"`
+		Path      []interface{} `json:"path"`
+		Locations []gqlLoc      `json:"locations"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, app *App, body string) (int, graphQLResult, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	app.handleGraphQL(rr, req)
+	var res graphQLResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("decode %s: %v", rr.Body.String(), err)
+	}
+	return rr.Code, res, rr.Body.String()
+}
+
+func TestGraphQLArtistWithNestedData(t *testing.T) {
+	app := graphQLTestApp()
+	code, res, raw := postGraphQL(t, app, `{"query":"query One($slug: String) { band: artist(slug: $slug) { name ...Extra events(limit: 2) { date city } } } fragment Extra on Artist { members { name } firstAlbumYear }","variables":{"slug":"queen"}}`)
+	if code != http.StatusOK || len(res.Errors) != 0 {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	var band struct {
+		Name           string `json:"name"`
+		Members        []struct{ Name string }
+		FirstAlbumYear int `json:"firstAlbumYear"`
+		Events         []struct {
+			Date string `json:"date"`
+			City string `json:"city"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(res.Data["band"], &band); err != nil {
+		t.Fatalf("decode band: %v", err)
+	}
+	if band.Name != "Queen" || len(band.Members) != 2 || band.FirstAlbumYear != 1973 {
+		t.Fatalf("unexpected artist %s", raw)
+	}
+	if len(band.Events) != 2 || band.Events[0].Date != "1980-01-01" {
+		t.Fatalf("unexpected events %s", raw)
+	}
+	if !strings.HasPrefix(raw, `{"data":{"band":{"name":"Queen","members"`) {
+		t.Fatalf("expected fields in query order, got %s", raw)
+	}
+}
+
+func TestGraphQLGetAndFilters(t *testing.T) {
+	app := graphQLTestApp()
+	req := httptest.NewRequest(http.MethodGet, `/graphql?query=`+url.QueryEscape(`{ artists(name: "pink") { id } events(country: "germany") { artist { slug } } }`), nil)
+	rr := httptest.NewRecorder()
+	app.handleGraphQL(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	want := `{"data":{"artists":[{"id":2}],"events":[{"artist":{"slug":"pink-floyd"}}]}}`
+	if strings.TrimSpace(rr.Body.String()) != want {
+		t.Fatalf("expected %s, got %s", want, rr.Body.String())
+	}
+}
+
+func TestGraphQLValidationErrors(t *testing.T) {
+	app := graphQLTestApp()
+	cases := map[string]string{
+		"syntax":   `{"query":"{ artists { name "}`,
+		"field":    `{"query":"{ artists { altitude } }"}`,
+		"argument": `{"query":"{ artists(colour: \"red\") { name } }"}`,
+		"leaf":     `{"query":"{ artists }"}`,
+		"depth":    `{"query":"{ artists { members { artists { members { artists { members { artists { name } } } } } } } }"}`,
+		"variable": `{"query":"query($id: Int!) { artist(id: $id) { name } }"}`,
+	}
+	for name, body := range cases {
+		code, res, raw := postGraphQL(t, app, body)
+		if code != http.StatusBadRequest || len(res.Errors) == 0 || res.Data != nil {
+			t.Fatalf("%s: expected a request error, got %d %s", name, code, raw)
+		}
+	}
+}
+
+// TestGraphQLChainedFragments sends 24 fragments that each spread the next
+// one twice. Expanding every spread would take 2^24 steps; validation and
+// execution must look at each fragment once instead.
+func TestGraphQLChainedFragments(t *testing.T) {
+	var query strings.Builder
+	query.WriteString("{ artists { ...F0 } }")
+	for i := 0; i < 24; i++ {
+		fmt.Fprintf(&query, " fragment F%d on Artist { ...F%d ...F%d }", i, i+1, i+1)
+	}
+	query.WriteString(" fragment F24 on Artist { name }")
+	body, _ := json.Marshal(graphQLRequest{Query: query.String()})
+
+	code, res, raw := postGraphQL(t, graphQLTestApp(), string(body))
+	if code != http.StatusOK || len(res.Errors) != 0 || string(res.Data["artists"]) != `[{"name":"Queen"},{"name":"Pink Floyd"}]` {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+}
+
+func TestGraphQLSelectionLimit(t *testing.T) {
+	var query strings.Builder
+	query.WriteString("{ artists {")
+	for i := 0; i <= gqlMaxSelections; i++ {
+		fmt.Fprintf(&query, " a%d: name", i)
+	}
+	query.WriteString(" } }")
+	body, _ := json.Marshal(graphQLRequest{Query: query.String()})
+
+	code, res, raw := postGraphQL(t, graphQLTestApp(), string(body))
+	if code != http.StatusBadRequest || len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "sélections") {
+		t.Fatalf("expected the selection limit error, got %d %.200s", code, raw)
+	}
+}
+
+func TestGraphQLArtistWithoutConcerts(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{Artists: []Artist{{ID: 7, Name: "Silent Band", Members: []string{"Nobody"}}}})
+	code, res, raw := postGraphQL(t, app, `{"query":"{ artists { name events { date } locations { city } } }"}`)
+	if code != http.StatusOK || len(res.Errors) != 0 {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	if got := string(res.Data["artists"]); got != `[{"name":"Silent Band","events":[],"locations":[]}]` {
+		t.Fatalf("unexpected artists %s", got)
+	}
+}
+
+func TestGraphQLResolverErrorKeepsPartialData(t *testing.T) {
+	app := graphQLTestApp()
+	code, res, raw := postGraphQL(t, app, `{"query":"{ missing: artist { name } artist(id: 2) { name } }"}`)
+	if code != http.StatusOK || len(res.Errors) != 1 {
+		t.Fatalf("expected one field error, got %d %s", code, raw)
+	}
+	if string(res.Data["missing"]) != "null" || string(res.Data["artist"]) != `{"name":"Pink Floyd"}` {
+		t.Fatalf("unexpected data %s", raw)
+	}
+	if len(res.Errors[0].Path) != 1 || res.Errors[0].Path[0] != "missing" {
+		t.Fatalf("expected error path, got %s", raw)
+	}
+}
+
+func TestGraphQLNonNullErrorPropagates(t *testing.T) {
+	app := graphQLTestApp()
+	code, res, raw := postGraphQL(t, app, `{"query":"{ artists(dateFrom: \"soon\") { name } }"}`)
+	if code != http.StatusOK || len(res.Errors) != 1 || !strings.Contains(raw, `"data":null`) {
+		t.Fatalf("expected null data with one error, got %d %s", code, raw)
+	}
+	if !strings.Contains(res.Errors[0].Message, "date_from") {
+		t.Fatalf("expected the filter message, got %s", raw)
+	}
+}
+
+func TestGraphQLIntrospection(t *testing.T) {
+	app := graphQLTestApp()
+	code, res, raw := postGraphQL(t, app, `{"query":"{ __schema { queryType { name } types { name } } __type(name: \"Artist\") { kind fields { name type { kind ofType { name } } } } }"}`)
+	if code != http.StatusOK || len(res.Errors) != 0 {
+		t.Fatalf("unexpected response %d %s", code, raw)
+	}
+	for _, name := range []string{`"Query"`, `"Artist"`, `"Member"`, `"Location"`, `"Event"`, `"SpotifyArtist"`, `"EventWindow"`, `"__Type"`} {
+		if !strings.Contains(string(res.Data["__schema"]), name) {
+			t.Fatalf("expected type %s in %s", name, raw)
+		}
+	}
+	if !strings.Contains(string(res.Data["__type"]), `{"name":"events","type":{"kind":"NON_NULL","ofType":{"name":null}}}`) {
+		t.Fatalf("unexpected Artist type %s", raw)
+	}
+}
+
+// graphiQLIntrospection is the query GraphiQL and most clients send.
+const graphiQLIntrospection = `query IntrospectionQuery {
+  __schema {
+    queryType { name } mutationType { name } subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) { name description args { ...InputValue } type { ...TypeRef } isDeprecated deprecationReason }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+func TestGraphQLFullIntrospectionQuery(t *testing.T) {
+	body, _ := json.Marshal(graphQLRequest{Query: graphiQLIntrospection})
+	code, res, raw := postGraphQL(t, graphQLTestApp(), string(body))
+	if code != http.StatusOK || len(res.Errors) != 0 {
+		t.Fatalf("unexpected response %d %.300s", code, raw)
+	}
+	// Fields without arguments list none rather than null.
+	if !strings.Contains(string(res.Data["__schema"]), `"name":"name","description":null,"args":[]`) {
+		t.Fatalf("expected empty args lists in %.300s", raw)
+	}
+}
+
+func TestGraphQLSpotifyWithoutIntegration(t *testing.T) {
+	app := graphQLTestApp()
+	_, res, raw := postGraphQL(t, app, `{"query":"{ spotifyArtist(id: \"abc\") { name } }"}`)
+	if len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "Spotify") {
+		t.Fatalf("expected a Spotify error, got %s", raw)
+	}
+}
+
+func TestGraphQLMethodNotAllowed(t *testing.T) {
+	app := graphQLTestApp()
+	req := httptest.NewRequest(http.MethodDelete, "/graphql", nil)
+	rr := httptest.NewRecorder()
+	app.handleGraphQL(rr, req)
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rr.Code)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -34,7 +35,7 @@ type spotifyArtistDetail struct {
 
 func (a *App) handleRoot(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" && r.URL.Path != "/index.html" {
-		a.renderError(w, http.StatusNotFound)
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
 	a.ensureCache(r.Context())
@@ -46,71 +47,94 @@ func (a *App) handleRoot(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := a.renderTemplate(w, "index.html", data); err != nil {
 		log.Printf("render index: %v", err)
-		a.renderError(w, http.StatusInternalServerError)
+		a.renderError(w, r, http.StatusInternalServerError)
 	}
 }
 
 func (a *App) handleArtistPage(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/artist" && r.URL.Path != "/artist.html" {
-		a.renderError(w, http.StatusNotFound)
+	key := ""
+	switch {
+	case r.URL.Path == "/artist" || r.URL.Path == "/artist.html":
+		key = strings.TrimSpace(r.URL.Query().Get("id"))
+	case strings.HasPrefix(r.URL.Path, "/artist/"):
+		key = strings.Trim(strings.TrimPrefix(r.URL.Path, "/artist/"), "/")
+		if key == "" {
+			a.renderError(w, r, http.StatusNotFound)
+			return
+		}
+	default:
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
+	if key != "" {
+		a.ensureCache(r.Context())
+		art, ok := findArtist(a.cache.ArtistsWithMeta(), key)
+		if !ok {
+			a.renderError(w, r, http.StatusNotFound)
+			return
+		}
+		// Numeric and query-string URLs are kept working but point to the canonical slug.
+		if r.URL.Path != "/artist/"+art.Slug {
+			http.Redirect(w, r, "/artist/"+art.Slug, http.StatusMovedPermanently)
+			return
+		}
+	}
 	if err := a.renderTemplate(w, "artist.html", nil); err != nil {
 		log.Printf("render artist: %v", err)
-		a.renderError(w, http.StatusInternalServerError)
+		a.renderError(w, r, http.StatusInternalServerError)
 	}
 }
 
 func (a *App) handleSpotifyArtistPage(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/artist-spotify" && r.URL.Path != "/artist-spotify.html" {
-		a.renderError(w, http.StatusNotFound)
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
 	if err := a.renderTemplate(w, "artist_spotify.html", nil); err != nil {
 		log.Printf("render spotify artist: %v", err)
-		a.renderError(w, http.StatusInternalServerError)
+		a.renderError(w, r, http.StatusInternalServerError)
 	}
 }
 
 func (a *App) handleDatesPage(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/dates" && r.URL.Path != "/dates.html" {
-		a.renderError(w, http.StatusNotFound)
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
 	if err := a.renderTemplate(w, "dates.html", nil); err != nil {
 		log.Printf("render dates: %v", err)
-		a.renderError(w, http.StatusInternalServerError)
+		a.renderError(w, r, http.StatusInternalServerError)
 	}
 }
 
 func (a *App) handleLocationsPage(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/locations" && r.URL.Path != "/locations.html" {
-		a.renderError(w, http.StatusNotFound)
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
 	if err := a.renderTemplate(w, "locations.html", nil); err != nil {
 		log.Printf("render locations: %v", err)
-		a.renderError(w, http.StatusInternalServerError)
+		a.renderError(w, r, http.StatusInternalServerError)
 	}
 }
 
 func (a *App) handleRelationsPage(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/relations" && r.URL.Path != "/relations.html" {
-		a.renderError(w, http.StatusNotFound)
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
 	if err := a.renderTemplate(w, "relations.html", nil); err != nil {
 		log.Printf("render relations: %v", err)
-		a.renderError(w, http.StatusInternalServerError)
+		a.renderError(w, r, http.StatusInternalServerError)
 	}
 }
 
-func (a *App) handle404Page(w http.ResponseWriter, _ *http.Request) {
-	a.renderError(w, http.StatusNotFound)
+func (a *App) handle404Page(w http.ResponseWriter, r *http.Request) {
+	a.renderError(w, r, http.StatusNotFound)
 }
 
-func (a *App) handle500Page(w http.ResponseWriter, _ *http.Request) {
-	a.renderError(w, http.StatusInternalServerError)
+func (a *App) handle500Page(w http.ResponseWriter, r *http.Request) {
+	a.renderError(w, r, http.StatusInternalServerError)
 }
 
 func (a *App) handleHealth(w http.ResponseWriter, _ *http.Request) {
@@ -123,14 +147,37 @@ func (a *App) handleAPIArtists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	a.ensureCache(r.Context())
+	started := time.Now()
 	q := r.URL.Query()
-	nameFilter := strings.ToLower(strings.TrimSpace(q.Get("name")))
-	yearFilter, err := parseYear(q.Get("year"))
+	filters, err := parseArtistFilters(q)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	sortParam := strings.TrimSpace(q.Get("sort"))
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	facets, err := parseFacets(q.Get("facets"), artistFacets)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	selection, err := parseFieldSelection(q, artistFields, artistIncludes)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	format, err := negotiateFormat(w, r)
+	if err == nil {
+		err = checkExportOptions(format, facets, selection)
+	}
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "année invalide"})
+		writeParamError(w, r, err)
 		return
 	}
-	memberFilter := strings.TrimSpace(q.Get("member"))
 
 	sourceParam := strings.ToLower(strings.TrimSpace(q.Get("source")))
 	externalParam := strings.ToLower(strings.TrimSpace(q.Get("external")))
@@ -140,42 +187,64 @@ func (a *App) handleAPIArtists(w http.ResponseWriter, r *http.Request) {
 		includeGroupie = true
 	}
 	unifiedResponse := includeSpotify || sourceParam == "groupie"
-	limitParam := 0
-	if limitStr := strings.TrimSpace(q.Get("limit")); limitStr != "" {
-		limitParam, err = strconv.Atoi(limitStr)
-		if err != nil || limitParam < 0 {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limite invalide"})
+	// spotify_limit caps the Spotify lookup; limit is kept as a fallback for older clients.
+	spotifyLimit := page.Limit
+	if limitStr := strings.TrimSpace(q.Get("spotify_limit")); limitStr != "" {
+		spotifyLimit, err = strconv.Atoi(limitStr)
+		if err != nil || spotifyLimit < 0 {
+			writeParamError(w, r, newParamError("spotify_limit", codeInvalidLimit))
 			return
 		}
 	}
-	spotifyLimit := limitParam
 	if spotifyLimit <= 0 {
 		spotifyLimit = 8
 	}
 
-	artists := a.cache.ArtistsWithMeta()
-	filtered := make([]ArtistWithMeta, 0, len(artists))
-	for _, art := range artists {
-		if nameFilter != "" && !strings.Contains(strings.ToLower(art.Name), nameFilter) {
-			continue
-		}
-		if yearFilter > 0 && art.CreationDate != yearFilter {
-			continue
-		}
-		if memberFilter != "" && !containsMember(art.Members, memberFilter) {
-			continue
+	all := a.cache.ArtistsWithMeta()
+	filtered := filterArtists(all, filters)
+	if missing := missingArtistIDs(all, filters.IDs); len(missing) > 0 {
+		ids := make([]string, len(missing))
+		for i, id := range missing {
+			ids[i] = strconv.Itoa(id)
 		}
-		filtered = append(filtered, art)
+		w.Header().Set("X-Missing-Ids", strings.Join(ids, ","))
+	}
+	if filters.Fuzzy && sortParam == "" {
+		sortParam = "-matchScore"
+	}
+	if err := sortArtists(filtered, sortParam); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
+		return
 	}
 
 	// Legacy behaviour: only return Groupie Tracker data unless a unified response is requested.
 	if !unifiedResponse {
-		writeJSON(w, http.StatusOK, filtered)
+		a.recordSearch("artists", normalizeAnalyticsQuery(filters.Name, filters.Member), len(filtered), started)
+		start, end, next := page.Bounds(len(filtered))
+		if format == formatCSV {
+			writePageHeaders(w, r, len(filtered), next)
+			writeCSV(w, "artists", filtered[start:end], selectColumns(artistColumns, selection))
+			return
+		}
+		var results interface{} = filtered[start:end]
+		if selection.Active() {
+			if results, err = shapeList(filtered[start:end], selection, a.artistEmbedder(selection.Include), artistWithMetaID); err != nil {
+				log.Printf("shape artists: %v", err)
+				writeProblem(w, r, codeInternal)
+				return
+			}
+		}
+		writePageHeaders(w, r, len(filtered), next)
+		if facets != nil {
+			writeJSON(w, http.StatusOK, facetedResponse{Results: results, Facets: computeArtistFacets(filtered, facets)})
+			return
+		}
+		writeResults(w, format, "artists", results)
 		return
 	}
 
 	if a.spotify == nil && !includeGroupie {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "l'intégration Spotify n'est pas configurée"})
+		writeProblem(w, r, codeSpotifyDisabled)
 		return
 	}
 
@@ -187,13 +256,14 @@ func (a *App) handleAPIArtists(w http.ResponseWriter, r *http.Request) {
 	}
 
 	spotifyUnified := make([]UnifiedArtist, 0)
-	if includeSpotify && a.spotify != nil && nameFilter != "" {
+	if includeSpotify && a.spotify != nil && filters.Name != "" {
 		ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
 		defer cancel()
-		results, err := a.spotify.SearchArtists(ctx, nameFilter, spotifyLimit)
+		results, err := a.spotify.SearchArtists(ctx, filters.Name, spotifyLimit)
 		if err != nil {
 			log.Printf("spotify search failed: %v", err)
 		} else {
+			a.linkSpotifyMatches(results)
 			for _, sa := range results {
 				u := toUnifiedSpotify(sa)
 				if strings.TrimSpace(u.ImageURL) == "" || strings.TrimSpace(u.Name) == "" {
@@ -205,7 +275,28 @@ func (a *App) handleAPIArtists(w http.ResponseWriter, r *http.Request) {
 	}
 
 	merged := mergeUnifiedArtists(groupieUnified, spotifyUnified)
-	writeJSON(w, http.StatusOK, merged)
+	a.recordSearch("artists", normalizeAnalyticsQuery(filters.Name, filters.Member), len(merged), started)
+	start, end, next := page.Bounds(len(merged))
+	if format == formatCSV {
+		writePageHeaders(w, r, len(merged), next)
+		writeCSV(w, "artists", merged[start:end], selectColumns(unifiedArtistColumns, selection))
+		return
+	}
+	var results interface{} = merged[start:end]
+	if selection.Active() {
+		if results, err = shapeList(merged[start:end], selection, a.artistEmbedder(selection.Include), unifiedArtistID); err != nil {
+			log.Printf("shape artists: %v", err)
+			writeProblem(w, r, codeInternal)
+			return
+		}
+	}
+	writePageHeaders(w, r, len(merged), next)
+	if facets != nil {
+		// Facets describe the Groupie Tracker matches; Spotify results carry no such data.
+		writeJSON(w, http.StatusOK, facetedResponse{Results: results, Facets: computeArtistFacets(filtered, facets)})
+		return
+	}
+	writeResults(w, format, "artists", results)
 }
 
 func (a *App) handleAPIArtistByID(w http.ResponseWriter, r *http.Request) {
@@ -214,31 +305,60 @@ func (a *App) handleAPIArtistByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if !strings.HasPrefix(r.URL.Path, "/api/artists/") {
-		a.renderError(w, http.StatusNotFound)
+		a.renderError(w, r, http.StatusNotFound)
 		return
 	}
 	a.ensureCache(r.Context())
 	if len(a.cache.Snapshot().Artists) == 0 {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "service indisponible"})
+		writeProblem(w, r, codeUnavailable)
 		return
 	}
-	idStr := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/artists/"), "/")
-	if idStr == "" {
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/artists/"), "/")
+	if key == "" {
 		a.handleAPIArtists(w, r)
 		return
 	}
-	id, err := strconv.Atoi(idStr)
+	if strings.HasSuffix(key, "/events.ics") {
+		a.handleAPIArtistEventsICS(w, r, strings.TrimSuffix(key, "/events.ics"))
+		return
+	}
+	if strings.Contains(key, "/") {
+		writeProblem(w, r, codeInvalidArtistID)
+		return
+	}
+	selection, err := parseFieldSelection(r.URL.Query(), artistFields, artistIncludes)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "identifiant d'artiste invalide"})
+		writeParamError(w, r, err)
 		return
 	}
-	for _, art := range a.cache.ArtistsWithMeta() {
-		if art.ID == id {
-			writeJSON(w, http.StatusOK, art)
+	art, ok := findArtist(a.cache.ArtistsWithMeta(), key)
+	if !ok {
+		writeProblem(w, r, codeArtistNotFound)
+		return
+	}
+	if key != art.Slug {
+		target := "/api/artists/" + art.Slug
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+	if selection.Active() {
+		var included map[string]interface{}
+		if embed := a.artistEmbedder(selection.Include); embed != nil {
+			included = embed(art.ID)
+		}
+		shaped, err := shapeResource(art, selection, included)
+		if err != nil {
+			log.Printf("shape artist: %v", err)
+			writeProblem(w, r, codeInternal)
 			return
 		}
+		writeJSON(w, http.StatusOK, shaped)
+		return
 	}
-	writeJSON(w, http.StatusNotFound, map[string]string{"error": "artiste introuvable"})
+	writeJSON(w, http.StatusOK, art)
 }
 
 func (a *App) handleAPILocations(w http.ResponseWriter, r *http.Request) {
@@ -247,7 +367,47 @@ func (a *App) handleAPILocations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	a.ensureCache(r.Context())
-	snap := a.cache.Snapshot()
+	started := time.Now()
+	q := r.URL.Query()
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	format, err := negotiateFormat(w, r)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	countryFilter := q.Get("country")
+	cityFilter := q.Get("city")
+	artistFilter := q.Get("artist")
+
+	views := make([]viewLocation, 0)
+	for _, view := range buildLocationViews(a.cache.Snapshot()) {
+		if !textContains(view.Country, countryFilter) {
+			continue
+		}
+		if !textContains(view.City, cityFilter) {
+			continue
+		}
+		if !textContains(view.ArtistName, artistFilter) {
+			continue
+		}
+		views = append(views, view)
+	}
+	if err := sortBy(views, strings.TrimSpace(q.Get("sort")), locationSortKeys); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
+		return
+	}
+	a.recordSearch("locations", normalizeAnalyticsQuery(artistFilter, cityFilter, countryFilter), len(views), started)
+	start, end, next := page.Bounds(len(views))
+	writePageHeaders(w, r, len(views), next)
+	writeList(w, format, "locations", views[start:end], locationColumns)
+}
+
+// buildLocationViews lists every artist/location pair with its concert count.
+func buildLocationViews(snap DataBundle) []viewLocation {
 	names := make(map[int]string, len(snap.Artists))
 	for _, a := range snap.Artists {
 		names[a.ID] = a.Name
@@ -260,36 +420,22 @@ func (a *App) handleAPILocations(w http.ResponseWriter, r *http.Request) {
 		}
 		relCounts[rel.ID] = counts
 	}
-	q := r.URL.Query()
-	countryFilter := strings.ToLower(strings.TrimSpace(q.Get("country")))
-	cityFilter := strings.ToLower(strings.TrimSpace(q.Get("city")))
-	artistFilter := strings.ToLower(strings.TrimSpace(q.Get("artist")))
 
 	views := make([]viewLocation, 0)
 	for _, loc := range snap.Locations {
 		for _, slug := range loc.Locations {
 			name := splitLocationSlug(slug)
-			view := viewLocation{
+			views = append(views, viewLocation{
 				ArtistID:   loc.ID,
 				ArtistName: names[loc.ID],
 				City:       name.City,
 				Country:    name.Country,
 				Raw:        name.Raw,
 				EventCount: relCounts[loc.ID][slug],
-			}
-			if countryFilter != "" && !strings.Contains(strings.ToLower(view.Country), countryFilter) {
-				continue
-			}
-			if cityFilter != "" && !strings.Contains(strings.ToLower(view.City), cityFilter) {
-				continue
-			}
-			if artistFilter != "" && !strings.Contains(strings.ToLower(view.ArtistName), artistFilter) {
-				continue
-			}
-			views = append(views, view)
+			})
 		}
 	}
-	writeJSON(w, http.StatusOK, views)
+	return views
 }
 
 func (a *App) handleAPIDates(w http.ResponseWriter, r *http.Request) {
@@ -301,13 +447,23 @@ func (a *App) handleAPIDates(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	yearFilter, err := parseYear(q.Get("year"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "année invalide"})
+		writeParamError(w, r, newParamError("year", codeInvalidYear))
+		return
+	}
+	dates, err := parseDateRange(q, "date_from", "date_to")
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	format, err := negotiateFormat(w, r)
+	if err != nil {
+		writeParamError(w, r, err)
 		return
 	}
 
 	var filtered []DatesIndex
 	for _, entry := range a.cache.Snapshot().Dates {
-		if yearFilter == 0 {
+		if yearFilter == 0 && !dates.Active() {
 			filtered = append(filtered, entry)
 			continue
 		}
@@ -317,7 +473,10 @@ func (a *App) handleAPIDates(w http.ResponseWriter, r *http.Request) {
 			if err != nil {
 				continue
 			}
-			if ts.Year() == yearFilter {
+			if yearFilter > 0 && ts.Year() != yearFilter {
+				continue
+			}
+			if dates.Contains(ts) {
 				matching = append(matching, d)
 			}
 		}
@@ -328,7 +487,7 @@ func (a *App) handleAPIDates(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 	}
-	writeJSON(w, http.StatusOK, filtered)
+	writeList(w, format, "dates", filtered, datesColumns)
 }
 
 func (a *App) handleAPIRelation(w http.ResponseWriter, r *http.Request) {
@@ -342,11 +501,16 @@ func (a *App) handleAPIRelation(w http.ResponseWriter, r *http.Request) {
 	if idStr := strings.TrimSpace(q.Get("id")); idStr != "" {
 		parsed, err := strconv.Atoi(idStr)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "identifiant invalide"})
+			writeParamError(w, r, newParamError("id", codeInvalidID))
 			return
 		}
 		artistFilter = parsed
 	}
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
 	relations := a.cache.Snapshot().Relations
 	if artistFilter > 0 {
 		out := make([]Relation, 0, 1)
@@ -356,10 +520,15 @@ func (a *App) handleAPIRelation(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 		}
-		writeJSON(w, http.StatusOK, out)
+		relations = out
+	}
+	if err := sortBy(relations, strings.TrimSpace(q.Get("sort")), relationSortKeys); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
 		return
 	}
-	writeJSON(w, http.StatusOK, relations)
+	start, end, next := page.Bounds(len(relations))
+	writePageHeaders(w, r, len(relations), next)
+	writeJSON(w, http.StatusOK, relations[start:end])
 }
 
 func (a *App) handleAPIEvents(w http.ResponseWriter, r *http.Request) {
@@ -368,48 +537,276 @@ func (a *App) handleAPIEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	a.ensureCache(r.Context())
-	events := a.cache.Events()
+	started := time.Now()
 	q := r.URL.Query()
-	countryFilter := strings.ToLower(strings.TrimSpace(q.Get("country")))
-	cityFilter := strings.ToLower(strings.TrimSpace(q.Get("city")))
-	artistFilter := strings.ToLower(strings.TrimSpace(q.Get("artist")))
-	yearFilter, err := parseYear(q.Get("year"))
+	filters, err := parseEventFilters(q, a.currentTime())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	facets, err := parseFacets(q.Get("facets"), eventFacets)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	format, err := negotiateFormat(w, r)
+	if err == nil {
+		err = checkExportOptions(format, facets, fieldSelection{})
+	}
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "année invalide"})
+		writeParamError(w, r, err)
 		return
 	}
 
-	filtered := make([]Event, 0, len(events))
-	for _, ev := range events {
-		if countryFilter != "" && !strings.Contains(strings.ToLower(ev.Country), countryFilter) {
-			continue
-		}
-		if cityFilter != "" && !strings.Contains(strings.ToLower(ev.City), cityFilter) {
-			continue
+	page, err := parsePage(q, a.cache.Version())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+
+	filtered := filterEvents(a.cache.Events(), filters)
+	if err := sortBy(filtered, strings.TrimSpace(q.Get("sort")), eventSortKeys); err != nil {
+		writeParamError(w, r, newParamError("sort", codeInvalidSort))
+		return
+	}
+	a.recordSearch("events", normalizeAnalyticsQuery(filters.Artist, filters.City, filters.Country), len(filtered), started)
+	start, end, next := page.Bounds(len(filtered))
+	writePageHeaders(w, r, len(filtered), next)
+	if facets != nil {
+		writeJSON(w, http.StatusOK, facetedResponse{Results: filtered[start:end], Facets: computeEventFacets(filtered, facets)})
+		return
+	}
+	writeList(w, format, "events", filtered[start:end], eventColumns)
+}
+
+func (a *App) handleAPIMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	q := r.URL.Query()
+	nameFilter := normalizeMemberName(q.Get("name"))
+	minBands := 0
+	if minStr := strings.TrimSpace(q.Get("min_bands")); minStr != "" {
+		parsed, err := strconv.Atoi(minStr)
+		if err != nil || parsed < 0 {
+			writeParamError(w, r, newParamError("min_bands", codeInvalidBandCount))
+			return
 		}
-		if artistFilter != "" && !strings.Contains(strings.ToLower(ev.ArtistName), artistFilter) {
+		minBands = parsed
+	}
+
+	members := a.cache.Members()
+	filtered := make([]Member, 0, len(members))
+	for _, m := range members {
+		if nameFilter != "" && !strings.Contains(m.NormalizedName, nameFilter) {
 			continue
 		}
-		if yearFilter > 0 && ev.Date.Year() != yearFilter {
+		if len(m.Bands) < minBands {
 			continue
 		}
-		filtered = append(filtered, ev)
+		filtered = append(filtered, m)
 	}
 	writeJSON(w, http.StatusOK, filtered)
 }
 
+func (a *App) handleAPIMemberByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/members/") {
+		a.renderError(w, r, http.StatusNotFound)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/members/"), "/")
+	if id == "" {
+		a.handleAPIMembers(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	for _, m := range a.cache.Members() {
+		if m.ID == id {
+			writeJSON(w, http.StatusOK, m)
+			return
+		}
+	}
+	writeProblem(w, r, codeMemberNotFound)
+}
+
+func (a *App) handleAPICountries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	writeJSON(w, http.StatusOK, summarizeCountries(a.cache.Events()))
+}
+
+func (a *App) handleAPICountryByCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	code := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/countries/"), "/")
+	if code == "" {
+		a.handleAPICountries(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	detail, ok := buildCountryDetail(code, a.cache.ArtistsWithMeta(), a.cache.Events())
+	if !ok {
+		writeProblem(w, r, codeCountryNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (a *App) handleAPICities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	countryFilter := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("country")))
+	cities := summarizeCities(a.cache.Events())
+	if countryFilter != "" {
+		filtered := make([]CitySummary, 0, len(cities))
+		for _, c := range cities {
+			if c.CountryCode == countryFilter {
+				filtered = append(filtered, c)
+			}
+		}
+		cities = filtered
+	}
+	writeJSON(w, http.StatusOK, cities)
+}
+
+func (a *App) handleAPICityBySlug(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	slug := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/cities/"), "/")
+	if slug == "" {
+		a.handleAPICities(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	detail, ok := buildCityDetail(slug, a.cache.ArtistsWithMeta(), a.cache.Events())
+	if !ok {
+		writeProblem(w, r, codeCityNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (a *App) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	started := time.Now()
+	q := r.URL.Query()
+	types := make(map[string]bool)
+	if typeParam := strings.TrimSpace(q.Get("type")); typeParam != "" {
+		for _, t := range strings.Split(typeParam, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if _, ok := hitTypeOrder[t]; !ok {
+				writeParamError(w, r, newParamError("type", codeInvalidResultType))
+				return
+			}
+			types[t] = true
+		}
+	}
+	limit := 20
+	if limitStr := strings.TrimSpace(q.Get("limit")); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			writeParamError(w, r, newParamError("limit", codeLimitOutOfRange, 100))
+			return
+		}
+		limit = parsed
+	}
+
+	hits := a.cache.SearchIndex().Search(q.Get("q"), types)
+	a.recordSearch("search", normalizeAnalyticsQuery(q.Get("q")), len(hits), started)
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	writeJSON(w, http.StatusOK, hits)
+}
+
+func (a *App) handleAPISuggest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	q := r.URL.Query()
+	limit := 8
+	if limitStr := strings.TrimSpace(q.Get("limit")); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 20 {
+			writeParamError(w, r, newParamError("limit", codeLimitOutOfRange, 20))
+			return
+		}
+		limit = parsed
+	}
+
+	start := time.Now()
+	suggestions := a.cache.SuggestIndex().Suggest(q.Get("q"), limit)
+	elapsed := time.Since(start)
+	if elapsed > suggestBudget {
+		log.Printf("suggest %q took %s (budget %s)", q.Get("q"), elapsed, suggestBudget)
+	}
+	w.Header().Set("Server-Timing", fmt.Sprintf("suggest;dur=%.3f", float64(elapsed.Microseconds())/1000))
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, suggestions)
+}
+
+func (a *App) handleAPIQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	started := time.Now()
+	query := r.URL.Query().Get("q")
+	terms, err := parseQuery(query)
+	if err != nil {
+		p := newProblem(r, codeInvalidQuery, 0, err.Error())
+		var qe *queryError
+		if errors.As(err, &qe) {
+			_, reason := problemText(preferredLanguage(r), qe.Code, qe.Args...)
+			p = newProblem(r, codeInvalidQuery, qe.Pos, reason)
+			p.Position = &qe.Pos
+			p.Reason = qe.Code
+		}
+		p.Param = "q"
+		sendProblem(w, r, p)
+		return
+	}
+	artists, events := evaluateQuery(terms, a.cache.ArtistsWithMeta(), a.cache.Events())
+	// The raw query is kept (only trimmed and lower-cased) because folding would lose its operators.
+	a.recordSearch("query", analyticsQueryText(query), len(artists)+len(events), started)
+	writeJSON(w, http.StatusOK, queryResult{Query: query, Artists: artists, Events: events})
+}
+
 func (a *App) handleAPISpotifyArtist(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		methodNotAllowed(w, r)
 		return
 	}
 	if a.spotify == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "l'intégration Spotify n'est pas configurée"})
+		writeProblem(w, r, codeSpotifyDisabled)
 		return
 	}
 	id := strings.TrimSpace(r.URL.Query().Get("id"))
 	if id == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "l'identifiant est requis"})
+		writeProblem(w, r, codeMissingID)
 		return
 	}
 	ctx, cancel := context.WithTimeout(r.Context(), 8*time.Second)
@@ -417,10 +814,10 @@ func (a *App) handleAPISpotifyArtist(w http.ResponseWriter, r *http.Request) {
 	artist, err := a.spotify.GetArtist(ctx, id)
 	if err != nil {
 		log.Printf("spotify artist lookup failed: %v", err)
-		status, message := mapSpotifyError(err)
-		writeJSON(w, status, map[string]string{"error": message})
+		writeProblem(w, r, mapSpotifyError(err))
 		return
 	}
+	a.linkSpotifyMatches([]SpotifyArtist{artist})
 	view := spotifyArtistDetail{
 		ID:         artist.ID,
 		Name:       artist.Name,
@@ -433,14 +830,12 @@ func (a *App) handleAPISpotifyArtist(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, view)
 }
 
-func mapSpotifyError(err error) (int, string) {
+// mapSpotifyError returns the problem code of a failed Spotify lookup.
+func mapSpotifyError(err error) string {
 	if errors.Is(err, ErrSpotifyNotFound) {
-		return http.StatusNotFound, "artiste introuvable"
+		return codeArtistNotFound
 	}
-	if errors.Is(err, ErrSpotifyUpstream) {
-		return http.StatusBadGateway, "spotify indisponible"
-	}
-	return http.StatusBadGateway, "spotify indisponible"
+	return codeSpotifyUnavailable
 }
 
 func (a *App) renderTemplate(w http.ResponseWriter, name string, data interface{}) error {
@@ -448,7 +843,18 @@ func (a *App) renderTemplate(w http.ResponseWriter, name string, data interface{
 	return a.templates.ExecuteTemplate(w, name, data)
 }
 
-func (a *App) renderError(w http.ResponseWriter, status int) {
+// renderError answers with the error page, or with a problem object when the
+// client prefers JSON (API paths do unless HTML is explicitly ranked higher).
+func (a *App) renderError(w http.ResponseWriter, r *http.Request, status int) {
+	w.Header().Add("Vary", "Accept")
+	if !prefersHTML(r) {
+		code := codeInternal
+		if status == http.StatusNotFound {
+			code = codeNotFound
+		}
+		writeProblem(w, r, code)
+		return
+	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(status)
 	name := "500.html"
@@ -460,9 +866,20 @@ func (a *App) renderError(w http.ResponseWriter, status int) {
 	}
 }
 
+// methodNotAllowed sends a 405. Handlers accepting more than GET set Allow
+// beforehand.
 func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Allow", http.MethodGet)
-	http.Error(w, "méthode non autoriséee", http.StatusMethodNotAllowed)
+	if w.Header().Get("Allow") == "" {
+		w.Header().Set("Allow", http.MethodGet)
+	}
+	w.Header().Add("Vary", "Accept")
+	if prefersHTML(r) {
+		title, _ := problemText(preferredLanguage(r), codeMethodNotAllowed)
+		w.Header().Set("Content-Language", preferredLanguage(r))
+		http.Error(w, title, http.StatusMethodNotAllowed)
+		return
+	}
+	writeProblem(w, r, codeMethodNotAllowed)
 }
 
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
@@ -476,16 +893,86 @@ func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	}
 }
 
+// fuzzyArtistScore scores an artist against the name and member filters. When both
+// are set the weaker match wins, so a result has to satisfy each filter on its own.
+func fuzzyArtistScore(art ArtistWithMeta, name, member string, threshold float64) (float64, bool) {
+	score := 1.0
+	if name != "" {
+		score = fuzzyScore(name, art.Name)
+	}
+	if member != "" {
+		if s := bestFuzzyScore(member, art.Members); s < score {
+			score = s
+		}
+	}
+	return score, score >= threshold
+}
+
+// parseBool accepts the usual truthy query values ("1", "true", "yes", "on").
+func parseBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
 func containsMember(members []string, needle string) bool {
-	needle = strings.ToLower(needle)
 	for _, m := range members {
-		if strings.Contains(strings.ToLower(m), needle) {
+		if textContains(m, needle) {
 			return true
 		}
 	}
 	return false
 }
 
+// sortArtists orders artists in place. The key is a JSON field name, optionally
+// prefixed with "-" for descending order. Artists without a parsable first album
+// always sort last when ordering by album.
+func sortArtists(artists []ArtistWithMeta, key string) error {
+	if key == "" {
+		return nil
+	}
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	var less func(a, b ArtistWithMeta) bool
+	switch key {
+	case "name":
+		less = func(a, b ArtistWithMeta) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "creationDate":
+		less = func(a, b ArtistWithMeta) bool { return a.CreationDate < b.CreationDate }
+	case "firstAlbum":
+		less = func(a, b ArtistWithMeta) bool { return a.FirstAlbumDate.Before(b.FirstAlbumDate) }
+	case "yearsToFirstAlbum":
+		less = func(a, b ArtistWithMeta) bool { return *a.YearsToFirstAlbum < *b.YearsToFirstAlbum }
+	case "matchScore":
+		less = func(a, b ArtistWithMeta) bool { return a.score() < b.score() }
+	default:
+		return fmt.Errorf("unknown sort key %q", key)
+	}
+	needsAlbum := key == "firstAlbum" || key == "yearsToFirstAlbum"
+
+	sort.SliceStable(artists, func(i, j int) bool {
+		a, b := artists[i], artists[j]
+		if needsAlbum {
+			okA := a.HasFirstAlbum() && (key != "yearsToFirstAlbum" || a.YearsToFirstAlbum != nil)
+			okB := b.HasFirstAlbum() && (key != "yearsToFirstAlbum" || b.YearsToFirstAlbum != nil)
+			if okA != okB {
+				return okA
+			}
+			if !okA {
+				return false
+			}
+		}
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+	return nil
+}
+
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -499,7 +986,7 @@ func recoverMiddleware(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("panic recovered: %v", err)
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				writeProblem(w, r, codeInternal)
 			}
 		}()
 		next.ServeHTTP(w, r)
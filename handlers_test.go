@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func newTestApp() *App {
@@ -26,6 +27,26 @@ func newTestApp() *App {
 	}
 }
 
+// queenFloydBundle is the upstream data shared by the fixtures of the
+// GraphQL, fieldset and feed tests: Queen (ID 1) with concerts in London and
+// Paris, and Pink Floyd (ID 2) with one in Berlin.
+func queenFloydBundle() DataBundle {
+	return DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Queen", Image: "queen.jpg", Members: []string{"Freddie Mercury", "Brian May"}, CreationDate: 1970, FirstAlbum: "13-07-1973"},
+			{ID: 2, Name: "Pink Floyd", Image: "pf.jpg", Members: []string{"Roger Waters"}, CreationDate: 1965},
+		},
+		Locations: []LocationIndex{
+			{ID: 1, Locations: []string{"london-uk", "paris-france"}},
+			{ID: 2, Locations: []string{"berlin-germany"}},
+		},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{"london-uk": {"01-01-1980", "02-01-1980"}, "paris-france": {"05-03-1981"}}},
+			{ID: 2, DatesLocations: map[string][]string{"berlin-germany": {"10-10-1990"}}},
+		},
+	}
+}
+
 func TestHandleAPIArtistsFilters(t *testing.T) {
 	app := newTestApp()
 	app.cache.Set(DataBundle{
@@ -51,6 +72,140 @@ func TestHandleAPIArtistsFilters(t *testing.T) {
 	}
 }
 
+func TestHandleAPIArtistsAlbumRangeAndSort(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Alpha", CreationDate: 2000, FirstAlbum: "01-01-2005"},
+			{ID: 2, Name: "Beta", CreationDate: 1990, FirstAlbum: "01-01-1992"},
+			{ID: 3, Name: "Gamma", CreationDate: 1985, FirstAlbum: "01-01-1999"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?album_from=1995&sort=-firstAlbum", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rr.Code)
+	}
+	var artists []ArtistWithMeta
+	if err := json.NewDecoder(rr.Body).Decode(&artists); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(artists) != 2 || artists[0].Name != "Alpha" || artists[1].Name != "Gamma" {
+		t.Fatalf("unexpected order %+v", artists)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/artists?sort=unknown", nil)
+	rr = httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown sort, got %d", rr.Code)
+	}
+}
+
+func TestHandleAPIArtistsAlbumZeroIsUnset(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Dated", FirstAlbum: "01-01-2005"},
+			{ID: 2, Name: "Undated", FirstAlbum: "unknown"},
+		},
+	})
+	for query, want := range map[string]int{
+		"album_from=0":               2,
+		"album_to=0":                 2,
+		"album_from=0&album_to=0":    2,
+		"album_from=2000&album_to=0": 1,
+	} {
+		rr := httptest.NewRecorder()
+		app.handleAPIArtists(rr, httptest.NewRequest(http.MethodGet, "/api/artists?"+query, nil))
+		var artists []ArtistWithMeta
+		if err := json.NewDecoder(rr.Body).Decode(&artists); err != nil {
+			t.Fatalf("%s: decode: %v", query, err)
+		}
+		if rr.Code != http.StatusOK || len(artists) != want {
+			t.Errorf("%s: expected %d artists, got %d %+v", query, want, rr.Code, artists)
+		}
+	}
+}
+
+func TestHandleAPIArtistsRanges(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Solo", Members: []string{"A"}, CreationDate: 1995},
+			{ID: 2, Name: "Duo", Members: []string{"A", "B"}, CreationDate: 2001},
+			{ID: 3, Name: "Quartet", Members: []string{"A", "B", "C", "D"}, CreationDate: 2003},
+		},
+		Relations: []Relation{
+			{ID: 2, DatesLocations: map[string][]string{"paris-france": {"05-06-2019"}}},
+			{ID: 3, DatesLocations: map[string][]string{"paris-france": {"05-06-2021"}}},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?creation_from=2000&members_min=2&members_max=3&date_from=2019-01-01&date_to=2019-12-31", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	var artists []ArtistWithMeta
+	if err := json.NewDecoder(rr.Body).Decode(&artists); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(artists) != 1 || artists[0].Name != "Duo" {
+		t.Fatalf("unexpected artists %+v", artists)
+	}
+
+	for _, query := range []string{"creation_from=2005&creation_to=2000", "members_min=-1", "date_from=2019-13-01", "date_from=2020-01-01&date_to=2019-01-01"} {
+		req = httptest.NewRequest(http.MethodGet, "/api/artists?"+query, nil)
+		rr = httptest.NewRecorder()
+		app.handleAPIArtists(rr, req)
+		var payload map[string]interface{}
+		if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if rr.Code != http.StatusBadRequest || payload["param"] == nil || payload["error"] == nil {
+			t.Fatalf("%s: expected 400 naming the parameter, got %d %v", query, rr.Code, payload)
+		}
+	}
+}
+
+func TestHandleAPIArtistsFuzzy(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Queens of the Stone Age"},
+			{ID: 2, Name: "Queen", Members: []string{"Freddie Mercury"}},
+			{ID: 3, Name: "Pink Floyd"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?name=quen&fuzzy=1&threshold=0.6", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	var artists []ArtistWithMeta
+	if err := json.NewDecoder(rr.Body).Decode(&artists); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(artists) != 2 || artists[0].Name != "Queen" || artists[0].MatchScore == nil {
+		t.Fatalf("expected Queen ranked first with a score, got %+v", artists)
+	}
+	if *artists[0].MatchScore <= *artists[1].MatchScore {
+		t.Fatalf("results not ranked by score: %+v", artists)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/artists?member=fredie%20mercuri&fuzzy=true", nil)
+	rr = httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	artists = nil
+	if err := json.NewDecoder(rr.Body).Decode(&artists); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(artists) != 1 || artists[0].ID != 2 {
+		t.Fatalf("expected fuzzy member match on Queen, got %+v", artists)
+	}
+}
+
 func TestHandleAPIEvents(t *testing.T) {
 	app := newTestApp()
 	app.cache.Set(DataBundle{
@@ -76,6 +231,124 @@ func TestHandleAPIEvents(t *testing.T) {
 	}
 }
 
+func TestHandleAPIEventsUpcomingWithTimeZone(t *testing.T) {
+	app := newTestApp()
+	app.now = func() time.Time { return time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC) }
+	app.cache.Set(DataBundle{
+		Artists: []Artist{{ID: 1, Name: "Delta"}},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{
+				"tokyo-japan":     {"10-03-2024"},
+				"los_angeles-usa": {"10-03-2024"},
+				"paris-france":    {"01-01-2020"},
+			}},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events?when=upcoming&tz=Europe/Paris", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIEvents(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", rr.Code)
+	}
+	var events []Event
+	if err := json.NewDecoder(rr.Body).Decode(&events); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected Tokyo and Los Angeles concerts, got %+v", events)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/events?tz=Mars/Olympus", nil)
+	rr = httptest.NewRecorder()
+	app.handleAPIEvents(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown zone, got %d", rr.Code)
+	}
+}
+
+func TestHandleAPIArtistBySlugAndRedirect(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{{ID: 7, Name: "Pink Floyd"}},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/artists/7", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtistByID(rr, req)
+	if rr.Code != http.StatusMovedPermanently || rr.Header().Get("Location") != "/api/artists/pink-floyd" {
+		t.Fatalf("expected redirect to slug, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/artists/pink-floyd", nil)
+	rr = httptest.NewRecorder()
+	app.handleAPIArtistByID(rr, req)
+	var art ArtistWithMeta
+	if err := json.NewDecoder(rr.Body).Decode(&art); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rr.Code != http.StatusOK || art.ID != 7 {
+		t.Fatalf("unexpected response %d %+v", rr.Code, art)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/artist?id=7", nil)
+	rr = httptest.NewRecorder()
+	app.handleArtistPage(rr, req)
+	if rr.Code != http.StatusMovedPermanently || rr.Header().Get("Location") != "/artist/pink-floyd" {
+		t.Fatalf("expected page redirect to slug, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestHandleAPILocationsAccentFolding(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists:   []Artist{{ID: 1, Name: "Motörhead"}},
+		Locations: []LocationIndex{{ID: 1, Locations: []string{"são_paulo-brazil", "london-uk"}}},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/locations?city=sao%20paulo&artist=motorhead", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPILocations(rr, req)
+	var views []viewLocation
+	if err := json.NewDecoder(rr.Body).Decode(&views); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(views) != 1 || views[0].Raw != "são_paulo-brazil" {
+		t.Fatalf("expected accent-insensitive location match, got %+v", views)
+	}
+}
+
+func TestHandleAPIEventsFacets(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{{ID: 1, Name: "Gamma"}},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{
+				"london-uk":    {"01-01-2020", "02-01-2021"},
+				"paris-france": {"03-01-2021"},
+			}},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events?year=2021&facets=concertCountry", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIEvents(rr, req)
+	var payload struct {
+		Results []Event                 `json:"results"`
+		Facets  map[string][]FacetValue `json:"facets"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(payload.Results) != 2 {
+		t.Fatalf("expected 2 events in 2021, got %+v", payload.Results)
+	}
+	countries := payload.Facets[facetConcertCountry]
+	if len(countries) != 2 || countries[0].Value != "france" || countries[1].Count != 1 {
+		t.Fatalf("facets not computed over the filtered set: %+v", payload.Facets)
+	}
+}
+
 func TestHandleRootNotFound(t *testing.T) {
 	app := newTestApp()
 	req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
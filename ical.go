@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalLineLimit is the maximum length of a content line in octets, excluding
+// the CRLF (RFC 5545 section 3.1).
+const icalLineLimit = 75
+
+// icalEscape escapes a TEXT value (RFC 5545 section 3.3.11).
+func icalEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', ';', ',':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// icalFold splits a content line into chunks of at most 75 octets, each
+// continuation starting with a space. Multi-byte characters are never split.
+func icalFold(line string) string {
+	if len(line) <= icalLineLimit {
+		return line
+	}
+	var b strings.Builder
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the limit of the next line.
+		limit = icalLineLimit - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// eventUID identifies a concert across exports: the same artist, location and
+// day always produce the same UID, so calendar clients update instead of
+// duplicating entries after a refresh.
+func eventUID(ev Event) string {
+	return fmt.Sprintf("%d-%s-%s@groupie-tracker", ev.ArtistID, ev.Location, strings.ReplaceAll(ev.DateISO, "-", ""))
+}
+
+// icalWriter emits folded content lines terminated by CRLF.
+type icalWriter struct {
+	w *bufio.Writer
+}
+
+func (iw icalWriter) line(name, value string) {
+	iw.w.WriteString(icalFold(name + ":" + value))
+	iw.w.WriteString("\r\n")
+}
+
+// writeICalendar writes events as a VCALENDAR of all-day VEVENTs. Dates are
+// the local concert day at the venue, which is how the upstream API lists them.
+func writeICalendar(out io.Writer, name string, events []Event, stamp time.Time) error {
+	iw := icalWriter{w: bufio.NewWriter(out)}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//Groupie Tracker//Concerts//FR")
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("METHOD", "PUBLISH")
+	iw.line("X-WR-CALNAME", icalEscape(name))
+	dtstamp := stamp.UTC().Format("20060102T150405Z")
+	for _, ev := range events {
+		day, err := time.Parse("2006-01-02", ev.LocalDate)
+		if err != nil {
+			continue
+		}
+		place := ev.City
+		if ev.Country != "" {
+			place += ", " + ev.Country
+		}
+		iw.line("BEGIN", "VEVENT")
+		iw.line("UID", eventUID(ev))
+		iw.line("DTSTAMP", dtstamp)
+		iw.line("DTSTART;VALUE=DATE", day.Format("20060102"))
+		iw.line("DTEND;VALUE=DATE", day.AddDate(0, 0, 1).Format("20060102"))
+		iw.line("SUMMARY", icalEscape(ev.ArtistName+" – "+place))
+		iw.line("LOCATION", icalEscape(place))
+		iw.line("DESCRIPTION", icalEscape(fmt.Sprintf("Concert de %s à %s (fuseau %s).", ev.ArtistName, place, ev.TimeZone)))
+		iw.line("TRANSP", "TRANSPARENT")
+		iw.line("END", "VEVENT")
+	}
+	iw.line("END", "VCALENDAR")
+	return iw.w.Flush()
+}
+
+// serveICalendar sends events as a text/calendar attachment.
+func (a *App) serveICalendar(w http.ResponseWriter, filename, name string, events []Event) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	if err := writeICalendar(w, name, events, a.currentTime()); err != nil {
+		log.Printf("write calendar: %v", err)
+	}
+}
+
+// handleAPIEventsICS serves /api/events.ics with the filters of /api/events.
+func (a *App) handleAPIEventsICS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	a.ensureCache(r.Context())
+	filters, err := parseEventFilters(r.URL.Query(), a.currentTime())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	a.serveICalendar(w, "events.ics", "Groupie Tracker – concerts", filterEvents(a.cache.Events(), filters))
+}
+
+// handleAPIArtistEventsICS serves /api/artists/{slug}/events.ics. Numeric IDs
+// are served directly rather than redirected, as some calendar clients do not
+// follow redirects on subscriptions.
+func (a *App) handleAPIArtistEventsICS(w http.ResponseWriter, r *http.Request, key string) {
+	filters, err := parseEventFilters(r.URL.Query(), a.currentTime())
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	art, ok := findArtist(a.cache.ArtistsWithMeta(), key)
+	if !ok {
+		writeProblem(w, r, codeArtistNotFound)
+		return
+	}
+	events := a.cache.Events()
+	own := make([]Event, 0, len(events)/8)
+	for _, ev := range events {
+		if ev.ArtistID == art.ID {
+			own = append(own, ev)
+		}
+	}
+	a.serveICalendar(w, art.Slug+".ics", art.Name+" – concerts", filterEvents(own, filters))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestICalFoldKeepsLinesShort(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("Concert à São Paulo, ", 10)
+	folded := icalFold(line)
+	for i, part := range strings.Split(folded, "\r\n") {
+		if len(part) > icalLineLimit {
+			t.Fatalf("line %d has %d octets: %q", i, len(part), part)
+		}
+		if i > 0 && !strings.HasPrefix(part, " ") {
+			t.Fatalf("continuation line %d must start with a space: %q", i, part)
+		}
+		if !utf8.ValidString(part) {
+			t.Fatalf("line %d splits a character: %q", i, part)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != line {
+		t.Fatalf("unfolding changed the content: %q", unfolded)
+	}
+}
+
+func TestICalEscape(t *testing.T) {
+	got := icalEscape("AC\\DC; live, Paris\nFrance")
+	want := `AC\\DC\; live\, Paris\nFrance`
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestArtistEventsICS(t *testing.T) {
+	app := fieldsetTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/artists/1/events.ics?date_from=1980-01-02", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtistByID(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Fatalf("unexpected calendar framing %q", body)
+	}
+	if strings.Count(body, "BEGIN:VEVENT") != 1 {
+		t.Fatalf("expected one filtered event, got %q", body)
+	}
+	for _, want := range []string{
+		"UID:1-london-uk-19800102@groupie-tracker\r\n",
+		"DTSTART;VALUE=DATE:19800102\r\n",
+		"DTEND;VALUE=DATE:19800103\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in %q", want, body)
+		}
+	}
+}
+
+func TestEventsICSValidatesFilters(t *testing.T) {
+	app := fieldsetTestApp()
+	req := httptest.NewRequest(http.MethodGet, "/api/events.ics?date_from=soon", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIEventsICS(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
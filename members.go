@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Member is a person listed in one or more artists' member lists.
+// The ID is derived from the normalised name so it stays stable across refreshes.
+type Member struct {
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	NormalizedName string       `json:"normalizedName"`
+	Bands          []MemberBand `json:"bands"`
+}
+
+// MemberBand references an artist a member belongs to.
+type MemberBand struct {
+	ArtistID   int    `json:"artistId"`
+	ArtistName string `json:"artistName"`
+}
+
+// normalizeMemberName folds a name with the shared text normalisation.
+func normalizeMemberName(name string) string {
+	return normalizeText(name)
+}
+
+// memberID turns a normalised name into a URL friendly identifier.
+func memberID(normalized string) string {
+	return strings.ReplaceAll(normalized, " ", "-")
+}
+
+// buildMembers groups artist member lists into Member entities, sorted by name.
+// People sharing the same normalised name are treated as the same person.
+func buildMembers(artists []Artist) []Member {
+	byID := make(map[string]*Member)
+	order := make([]string, 0)
+	for _, art := range artists {
+		for _, raw := range art.Members {
+			name := strings.TrimSpace(raw)
+			normalized := normalizeMemberName(name)
+			if normalized == "" {
+				continue
+			}
+			id := memberID(normalized)
+			m, ok := byID[id]
+			if !ok {
+				m = &Member{ID: id, Name: name, NormalizedName: normalized}
+				byID[id] = m
+				order = append(order, id)
+			}
+			if len(m.Bands) > 0 && m.Bands[len(m.Bands)-1].ArtistID == art.ID {
+				continue
+			}
+			m.Bands = append(m.Bands, MemberBand{ArtistID: art.ID, ArtistName: art.Name})
+		}
+	}
+
+	members := make([]Member, 0, len(order))
+	for _, id := range order {
+		members = append(members, *byID[id])
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].NormalizedName < members[j].NormalizedName
+	})
+	return members
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// foldedRunes maps accented and special letters to their ASCII base form.
+// The standard library has no Unicode decomposition, so the table covers the
+// Latin letters that show up in artist, member and city names.
+var foldedRunes = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'č': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ě': "e", 'ė': "e", 'ę': "e",
+	'ğ': "g", 'ģ': "g",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ķ': "k", 'ł': "l", 'ľ': "l", 'ļ': "l", 'ĺ': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n", 'ņ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ŕ': "r", 'ř': "r",
+	'ś': "s", 'š': "s", 'ş': "s", 'ș': "s", 'ß': "ss",
+	'ť': "t", 'ţ': "t", 'ț': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// isJoiner reports runes that are dropped without leaving a word break, so
+// "Guns N' Roses" folds to "guns n roses" and "R.E.M." to "rem".
+func isJoiner(r rune) bool {
+	return r == '\'' || r == '’' || r == '.'
+}
+
+// normalizeText is the shared folding used by every text filter: it lowercases,
+// removes diacritics, turns punctuation into spaces and collapses whitespace.
+func normalizeText(s string) string {
+	return fold(s, nil)
+}
+
+// normalizeWithOffsets folds s like normalizeText and also returns, for every
+// byte of the result, the index of the rune of s it came from. It lets callers
+// map a match in the folded text back onto the original string.
+func normalizeWithOffsets(s string) (string, []int) {
+	offsets := make([]int, 0, len(s))
+	return fold(s, &offsets), offsets
+}
+
+func fold(s string, offsets *[]int) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	emit := func(text string, from int) {
+		b.WriteString(text)
+		if offsets != nil {
+			for i := 0; i < len(text); i++ {
+				*offsets = append(*offsets, from)
+			}
+		}
+	}
+	space := true
+	pos := -1
+	for _, r := range s {
+		pos++
+		r = unicode.ToLower(r)
+		if folded, ok := foldedRunes[r]; ok {
+			emit(folded, pos)
+			space = false
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) || isJoiner(r) {
+			// Combining marks from decomposed input ("e\u0301") are dropped.
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			emit(string(r), pos)
+			space = false
+			continue
+		}
+		if !space {
+			emit(" ", pos)
+			space = true
+		}
+	}
+	out := b.String()
+	if trimmed := strings.TrimRight(out, " "); len(trimmed) != len(out) {
+		out = trimmed
+		if offsets != nil {
+			*offsets = (*offsets)[:len(out)]
+		}
+	}
+	return out
+}
+
+// textContains reports whether needle occurs in haystack after normalisation.
+// Spaces are also ignored as a fallback so "acdc" finds "AC/DC".
+func textContains(haystack, needle string) bool {
+	n := normalizeText(needle)
+	if n == "" {
+		return true
+	}
+	h := normalizeText(haystack)
+	if strings.Contains(h, n) {
+		return true
+	}
+	return strings.Contains(strings.ReplaceAll(h, " ", ""), strings.ReplaceAll(n, " ", ""))
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	tests := map[string]string{
+		"Beyoncé":           "beyonce",
+		"MOTÖRHEAD":         "motorhead",
+		"São  Paulo":        "sao paulo",
+		"sao_paulo-brazil":  "sao paulo brazil",
+		"Guns N' Roses":     "guns n roses",
+		"R.E.M.":            "rem",
+		"AC/DC":             "ac dc",
+		"Beyonce\u0301":     "beyonce",
+		"  Sigur   Rós !! ": "sigur ros",
+	}
+	for in, want := range tests {
+		if got := normalizeText(in); got != want {
+			t.Errorf("normalizeText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTextContains(t *testing.T) {
+	if !textContains("Beyoncé", "beyonce") || !textContains("São Paulo", "SAO PAULO") {
+		t.Fatalf("expected accent-insensitive match")
+	}
+	if !textContains("AC/DC", "acdc") {
+		t.Fatalf("expected punctuation-insensitive match")
+	}
+	if textContains("Queen", "king") {
+		t.Fatalf("unexpected match")
+	}
+}
@@ -0,0 +1,731 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// The OpenAPI document is generated at runtime: response schemas come from
+// the Go types the handlers encode, and the operation table below describes
+// each route's parameters. TestOpenAPICoversRoutes keeps it in sync with
+// apiRoutes.
+
+// apiParam documents one query or path parameter.
+type apiParam struct {
+	Name        string
+	In          string // "query" or "path"
+	Type        string // "string" (default), "integer", "number" or "boolean"
+	Description string
+	Enum        []string
+}
+
+// apiOperation documents one route, GET unless Method says otherwise.
+type apiOperation struct {
+	Path        string
+	Method      string
+	Summary     string
+	Params      []apiParam
+	Request     interface{}   // a value of the JSON request body type, if any
+	Response    interface{}   // a value of the response type
+	MediaType   string        // non-JSON body, e.g. text/calendar
+	Exports     bool          // also served as CSV and NDJSON
+	Envelope    bool          // v2: wrapped in {data, meta, links}
+	Alternates  []interface{} // other shapes the route may return (oneOf)
+	Paginated   bool
+	NotFound    bool
+	Redirects   bool
+	Unavailable bool
+	Admin       bool
+	// BadRequest is the body of 400 responses when it is not a problem.
+	BadRequest interface{}
+}
+
+func queryParam(name, typ, description string, enum ...string) apiParam {
+	return apiParam{Name: name, In: "query", Type: typ, Description: description, Enum: enum}
+}
+
+func pathParam(name, description string) apiParam {
+	return apiParam{Name: name, In: "path", Type: "string", Description: description}
+}
+
+var (
+	paramLimit  = queryParam("limit", "integer", "Page size (max 500); omitted returns every result.")
+	paramCursor = queryParam("cursor", "string", "Opaque cursor from X-Next-Cursor. Keep the other parameters unchanged.")
+	paramFormat = queryParam("format", "string", "Response format; overrides the Accept header.", formatJSON, formatCSV, formatNDJSON)
+)
+
+func sortQueryParam(keys ...string) apiParam {
+	enum := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		enum = append(enum, k, "-"+k)
+	}
+	return queryParam("sort", "string", "Sort key, prefix with - for descending order.", enum...)
+}
+
+var dateRangeParams = []apiParam{
+	queryParam("date_from", "string", "First day included (YYYY-MM-DD)."),
+	queryParam("date_to", "string", "Last day included (YYYY-MM-DD)."),
+}
+
+func artistListParams() []apiParam {
+	params := []apiParam{
+		queryParam("name", "string", "Artist name contains this text (accents and case ignored)."),
+		queryParam("member", "string", "A member name contains this text."),
+		queryParam("year", "integer", "Creation year."),
+		queryParam("creation_from", "integer", "Minimum creation year."),
+		queryParam("creation_to", "integer", "Maximum creation year."),
+		queryParam("album_from", "integer", "Minimum first album year; 0 means unset."),
+		queryParam("album_to", "integer", "Maximum first album year; 0 means unset."),
+		queryParam("members_min", "integer", "Minimum number of members."),
+		queryParam("members_max", "integer", "Maximum number of members."),
+	}
+	params = append(params, dateRangeParams...)
+	return append(params,
+		queryParam("fuzzy", "boolean", "Typo tolerant name and member matching."),
+		queryParam("threshold", "number", "Minimum fuzzy similarity between 0 and 1 (default 0.7); implies fuzzy."),
+		queryParam("ids", "string", "Comma list of up to 100 artist IDs; results keep this order unless sort is given."),
+		queryParam("source", "string", "Return the unified shape from Groupie Tracker, Spotify or both.", sourceGroupie, sourceSpotify, "all"),
+		queryParam("external", "string", "Add Spotify matches to the Groupie Tracker results.", sourceSpotify),
+		queryParam("spotify_limit", "integer", "Maximum number of Spotify matches (default 8)."),
+		sortQueryParam("name", "creationDate", "firstAlbum", "yearsToFirstAlbum", "matchScore"),
+		queryParam("facets", "string", "all or a comma list of "+strings.Join(artistFacets, ", ")+"."),
+		queryParam("fields", "string", "Comma list of fields to return; id and slug are always kept."),
+		queryParam("include", "string", "Comma list of related resources to embed: "+strings.Join(artistIncludes, ", ")+"."),
+		paramLimit,
+		paramCursor,
+		paramFormat,
+	)
+}
+
+// withoutParams drops the named parameters.
+func withoutParams(params []apiParam, names ...string) []apiParam {
+	out := make([]apiParam, 0, len(params))
+	for _, p := range params {
+		keep := true
+		for _, name := range names {
+			keep = keep && p.Name != name
+		}
+		if keep {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// apiOperations describes every API route, keyed by its OpenAPI path.
+func apiOperations() []apiOperation {
+	eventParams := []apiParam{
+		queryParam("country", "string", "Country contains this text."),
+		queryParam("city", "string", "City contains this text."),
+		queryParam("artist", "string", "Artist name contains this text."),
+		queryParam("year", "integer", "Concert year."),
+	}
+	eventParams = append(eventParams, dateRangeParams...)
+	eventParams = append(eventParams,
+		queryParam("when", "string", "Time window relative to now.", windowToday, windowUpcoming, windowPast),
+		queryParam("tz", "string", "IANA time zone used for today (default UTC)."),
+	)
+	icalParams := append([]apiParam{}, eventParams...)
+	feedParams := append(withoutParams(icalParams, "artist"),
+		queryParam("artist", "string", "Artist slug or ID."),
+		queryParam("limit", "integer", "Maximum entries (default 50, max 200)."),
+	)
+	eventParams = append(eventParams,
+		sortQueryParam("date", "artistName", "city", "country"),
+		queryParam("facets", "string", "all or a comma list of "+strings.Join(eventFacets, ", ")+"."),
+		paramLimit,
+		paramCursor,
+		paramFormat,
+	)
+
+	return []apiOperation{
+		{
+			Path: "/api/artists", Summary: "List artists", Params: artistListParams(),
+			Response:   []ArtistWithMeta{},
+			Alternates: []interface{}{[]UnifiedArtist{}, facetedResponse{}},
+			Exports:    true,
+			Paginated:  true, Unavailable: true,
+		},
+		{
+			Path: "/api/artists/{slug}", Summary: "Get an artist",
+			Params: []apiParam{
+				pathParam("slug", "Artist slug; numeric IDs redirect to the slug."),
+				queryParam("fields", "string", "Comma list of fields to return; id and slug are always kept."),
+				queryParam("include", "string", "Comma list of related resources to embed: "+strings.Join(artistIncludes, ", ")+"."),
+			},
+			Response: ArtistWithMeta{}, NotFound: true, Redirects: true, Unavailable: true,
+		},
+		{
+			Path: "/api/artists/{slug}/events.ics", Summary: "An artist's concerts as an iCalendar feed",
+			Params:    append([]apiParam{pathParam("slug", "Artist slug or numeric ID.")}, icalParams...),
+			MediaType: "text/calendar", NotFound: true, Unavailable: true,
+		},
+		{
+			Path: "/api/members", Summary: "List band members",
+			Params: []apiParam{
+				queryParam("name", "string", "Member name contains this text."),
+				queryParam("min_bands", "integer", "Minimum number of bands."),
+			},
+			Response: []Member{},
+		},
+		{
+			Path: "/api/members/{id}", Summary: "Get a band member and their bands",
+			Params:   []apiParam{pathParam("id", "Member ID.")},
+			Response: Member{}, NotFound: true,
+		},
+		{
+			Path: "/api/locations", Summary: "List artist locations",
+			Params: []apiParam{
+				queryParam("country", "string", "Country contains this text."),
+				queryParam("city", "string", "City contains this text."),
+				queryParam("artist", "string", "Artist name contains this text."),
+				sortQueryParam("artistName", "city", "country", "eventCount"),
+				paramLimit,
+				paramCursor,
+				paramFormat,
+			},
+			Response: []viewLocation{}, Paginated: true, Exports: true,
+		},
+		{
+			Path: "/api/dates", Summary: "List concert dates per artist",
+			Params:   append(append([]apiParam{queryParam("year", "integer", "Concert year.")}, dateRangeParams...), paramFormat),
+			Response: []DatesIndex{}, Exports: true,
+		},
+		{
+			Path: "/api/relation", Summary: "List concert dates per location and artist",
+			Params: []apiParam{
+				queryParam("id", "integer", "Artist ID."),
+				sortQueryParam("id"),
+				paramLimit,
+				paramCursor,
+			},
+			Response: []Relation{}, Paginated: true,
+		},
+		{
+			Path: "/api/events", Summary: "List concerts", Params: eventParams,
+			Response: []Event{}, Alternates: []interface{}{facetedResponse{}}, Paginated: true, Exports: true,
+		},
+		{
+			Path: "/api/events.ics", Summary: "Concerts as an iCalendar feed", Params: icalParams,
+			MediaType: "text/calendar",
+		},
+		{
+			Path: "/api/search", Summary: "Full-text search",
+			Params: []apiParam{
+				queryParam("q", "string", "Search text."),
+				queryParam("type", "string", "Comma list of hit types: artist, member, location, event."),
+				queryParam("limit", "integer", "Maximum number of hits (default 20, max 100)."),
+			},
+			Response: []SearchHit{},
+		},
+		{
+			Path: "/api/suggest", Summary: "Autocomplete suggestions",
+			Params: []apiParam{
+				queryParam("q", "string", "Prefix typed so far."),
+				queryParam("limit", "integer", "Maximum number of suggestions (default 8, max 20)."),
+			},
+			Response: []Suggestion{},
+		},
+		{
+			Path: "/api/query", Summary: "Structured query",
+			Params:   []apiParam{queryParam("q", "string", `Query such as member:freddie country:uk year>=1980 -city:london "pink floyd".`)},
+			Response: queryResult{},
+		},
+		{
+			Path: "/api/countries", Summary: "List countries with concerts",
+			Response: []CountrySummary{},
+		},
+		{
+			Path: "/api/countries/{code}", Summary: "Get a country",
+			Params:   []apiParam{pathParam("code", "Country part of a location slug, e.g. usa.")},
+			Response: CountryDetail{}, NotFound: true,
+		},
+		{
+			Path: "/api/cities", Summary: "List cities with concerts",
+			Params:   []apiParam{queryParam("country", "string", "Country code.")},
+			Response: []CitySummary{},
+		},
+		{
+			Path: "/api/cities/{slug}", Summary: "Get a city",
+			Params:   []apiParam{pathParam("slug", "Upstream location, e.g. los_angeles-usa.")},
+			Response: CityDetail{}, NotFound: true,
+		},
+		{
+			Path: "/api/spotify/artist", Summary: "Get a Spotify artist",
+			Params:   []apiParam{queryParam("id", "string", "Spotify artist ID.")},
+			Response: spotifyArtistDetail{}, NotFound: true, Unavailable: true,
+		},
+		{
+			Path: "/api/admin/search-stats", Summary: "Popular, zero-result and trending queries",
+			Params: []apiParam{
+				queryParam("endpoint", "string", "Restrict the report to one endpoint."),
+				queryParam("limit", "integer", "Maximum entries per list (default 20, max 100)."),
+			},
+			Response: AnalyticsReport{}, NotFound: true, Admin: true,
+		},
+		{
+			Path: "/api/openapi.json", Summary: "This document",
+			Response: map[string]interface{}{},
+		},
+		{
+			Path: schemasPath, Summary: "List the JSON Schemas of the response types",
+			Response: []schemaLink{},
+		},
+		{
+			Path: schemasPath + "/{name}", Summary: "Get a JSON Schema (2020-12) document",
+			Params:   []apiParam{pathParam("name", "Schema name, e.g. ArtistWithMeta.json; the .json extension is optional.")},
+			Response: map[string]interface{}{}, NotFound: true,
+		},
+		{
+			Path: "/graphql", Summary: "GraphQL query with query, operationName and variables parameters",
+			Params: []apiParam{
+				queryParam("query", "string", "GraphQL document."),
+				queryParam("operationName", "string", "Operation to run when the document has several."),
+				queryParam("variables", "string", "Variables as a JSON object."),
+			},
+			Response: gqlExecuted{}, BadRequest: gqlResponse{},
+		},
+		{
+			Path: "/graphql", Method: http.MethodPost, Summary: "GraphQL query as JSON (or application/graphql)",
+			Request: graphQLRequest{}, Response: gqlExecuted{}, BadRequest: gqlResponse{},
+		},
+		{
+			Path: "/feeds/concerts.atom", Summary: "Newly announced concerts as an Atom feed",
+			Params: feedParams, MediaType: "application/atom+xml", NotFound: true,
+		},
+		{
+			Path: "/feeds/concerts.rss", Summary: "Newly announced concerts as an RSS 2.0 feed",
+			Params: feedParams, MediaType: "application/rss+xml", NotFound: true,
+		},
+		{
+			Path: "/healthz", Summary: "Liveness check",
+			Response: map[string]string{},
+		},
+		{
+			Path: streamPath, Summary: "Server-Sent Events on data refreshes, concert changes and Spotify links",
+			Params: []apiParam{
+				queryParam("types", "string", "Comma list of event types: "+strings.Join(streamTypes, ", ")+"."),
+				queryParam("artist", "string", "Comma list of artist slugs or IDs; other artists' events are skipped."),
+				queryParam("country", "string", "Comma list of countries; concerts elsewhere are skipped."),
+				queryParam("last_event_id", "integer", "Resume after this event, for clients that cannot send Last-Event-ID."),
+			},
+			MediaType: "text/event-stream", Unavailable: true,
+		},
+		{
+			Path: batchPath, Method: http.MethodPost, Summary: "Run several GET /api requests against one data snapshot",
+			Request: batchRequest{}, Response: batchResponse{},
+		},
+		{
+			Path: apiV2Prefix + "/artists", Summary: "List artists (v2)",
+			Params: append(withoutParams(artistListParams(), "external", "spotify_limit", "fields", "include", "format"),
+				queryParam("source", "string", "Artists from Groupie Tracker (default), Spotify or both. With spotify or all, only the first page is returned and cursor is rejected.", sourceGroupie, sourceSpotify, "all")),
+			Response: []artistV2{}, Envelope: true, Unavailable: true,
+		},
+		{
+			Path: apiV2Prefix + "/artists/{id}", Summary: "Get an artist (v2)",
+			Params:   []apiParam{pathParam("id", "Typed ID (groupie:1, spotify:...), slug or numeric ID.")},
+			Response: artistV2{}, Envelope: true, NotFound: true, Unavailable: true,
+		},
+		{
+			Path: apiV2Prefix + "/members", Summary: "List band members (v2)",
+			Params: []apiParam{
+				queryParam("name", "string", "Member name contains this text."),
+				queryParam("min_bands", "integer", "Minimum number of bands."),
+				paramLimit,
+				paramCursor,
+			},
+			Response: []memberV2{}, Envelope: true,
+		},
+		{
+			Path: apiV2Prefix + "/members/{id}", Summary: "Get a band member (v2)",
+			Params:   []apiParam{pathParam("id", "Member ID.")},
+			Response: memberV2{}, Envelope: true, NotFound: true,
+		},
+		{
+			Path: apiV2Prefix + "/locations", Summary: "List artist locations (v2)",
+			Params: []apiParam{
+				queryParam("country", "string", "Country contains this text."),
+				queryParam("city", "string", "City contains this text."),
+				queryParam("artist", "string", "Artist name contains this text."),
+				sortQueryParam("artistName", "city", "country", "eventCount"),
+				paramLimit,
+				paramCursor,
+			},
+			Response: []locationV2{}, Envelope: true,
+		},
+		{
+			Path: apiV2Prefix + "/locations/{id}", Summary: "Get an artist location (v2)",
+			Params:   []apiParam{pathParam("id", "Typed location ID, e.g. groupie:1:london-uk.")},
+			Response: locationV2{}, Envelope: true, NotFound: true,
+		},
+		{
+			Path: apiV2Prefix + "/events", Summary: "List concerts (v2)",
+			Params:   withoutParams(eventParams, "format"),
+			Response: []eventV2{}, Envelope: true,
+		},
+		{
+			Path: apiV2Prefix + "/events/{id}", Summary: "Get a concert (v2)",
+			Params:   []apiParam{pathParam("id", "Typed event ID, e.g. groupie:1:london-uk:1980-01-01.")},
+			Response: eventV2{}, Envelope: true, NotFound: true,
+		},
+	}
+}
+
+// schemaBuilder turns Go types into JSON Schemas, registering named structs
+// under components/schemas. With draft set it emits standalone JSON Schema
+// (2020-12) instead of the OpenAPI 3.0 dialect: named structs go under $defs,
+// objects reject unknown properties, and fields Go may encode as null say so.
+type schemaBuilder struct {
+	components map[string]interface{}
+	draft      bool
+}
+
+// fieldNote documents a field beyond its Go type.
+type fieldNote struct {
+	Format      string
+	Description string
+}
+
+// fieldNotes is keyed by schema name and JSON field name.
+var fieldNotes = map[string]fieldNote{
+	"Artist.creationDate":           {Description: "Year the band was formed."},
+	"Artist.firstAlbum":             {Description: "First album release date as sent upstream, DD-MM-YYYY."},
+	"Artist.locations":              {Description: "Upstream URL of the artist's locations."},
+	"Artist.concertDates":           {Description: "Upstream URL of the artist's concert dates."},
+	"Artist.relations":              {Description: "Upstream URL of the artist's relation."},
+	"ArtistWithMeta.locations":      {Description: "Location slugs, city-country."},
+	"ArtistWithMeta.dates":          {Description: "Concert dates, DD-MM-YYYY."},
+	"ArtistWithMeta.datesLocations": {Description: "Concert dates (DD-MM-YYYY) by location slug."},
+	"ArtistWithMeta.firstAlbumDate": {Format: "date", Description: "firstAlbum as YYYY-MM-DD; absent when it cannot be parsed."},
+	"Event.date":                    {Format: "date", Description: "Concert day, YYYY-MM-DD."},
+	"Event.localDate":               {Format: "date", Description: "Concert day at the venue, YYYY-MM-DD."},
+	"Event.timeZone":                {Description: "IANA time zone of the venue."},
+	"Event.startsAt":                {Description: "Start of the concert day at the venue, in UTC."},
+	"Relation.datesLocations":       {Description: "Concert dates (DD-MM-YYYY) by location slug."},
+	"UnifiedArtist.id":              {Description: "Groupie Tracker ID or Spotify artist ID, as a string."},
+	"UnifiedArtist.source":          {Description: "groupie or spotify."},
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+func schemaName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{} // any JSON value
+	case t.Kind() == reflect.Ptr && b.draft:
+		// object decides whether the field can be null.
+		return b.schema(t.Elem())
+	case t.Kind() == reflect.Ptr:
+		s := b.schema(t.Elem())
+		s["nullable"] = true
+		return s
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = nil // reserve the name for recursive types
+			b.components[name] = b.object(t)
+		}
+		if b.draft {
+			return map[string]interface{}{"$ref": "#/$defs/" + name}
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object describes a struct the way encoding/json encodes it: direct fields
+// win over fields promoted from embedded structs.
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	required := make([]string, 0)
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, f.Type)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if name == "" {
+			name = f.Name
+		}
+		prop := b.schema(f.Type)
+		omitempty := false
+		for _, opt := range parts[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+		if !omitempty {
+			required = append(required, name)
+		}
+		if note, ok := fieldNotes[schemaName(t)+"."+name]; ok {
+			if note.Format != "" {
+				prop["format"] = note.Format
+			}
+			if note.Description != "" {
+				prop["description"] = note.Description
+			}
+		}
+		// encoding/json writes nil slices, maps and pointers as null unless
+		// omitempty drops them.
+		switch f.Type.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Ptr:
+			if b.draft && !omitempty {
+				prop = orNull(prop)
+			}
+		}
+		props[name] = prop
+	}
+	for _, et := range embedded {
+		inner := b.object(et)
+		for name, s := range inner["properties"].(map[string]interface{}) {
+			if _, ok := props[name]; ok {
+				continue
+			}
+			props[name] = s
+			if req, ok := inner["required"].([]string); ok {
+				for _, r := range req {
+					if r == name {
+						required = append(required, name)
+					}
+				}
+			}
+		}
+	}
+	out := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		out["required"] = required
+	}
+	if b.draft {
+		out["additionalProperties"] = false
+	}
+	return out
+}
+
+// orNull also accepts null in a draft schema.
+func orNull(s map[string]interface{}) map[string]interface{} {
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []string{typ, "null"}
+		return s
+	}
+	return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+}
+
+// jsonBody returns the schema of a JSON response, a oneOf when the route has
+// alternate shapes.
+func (b *schemaBuilder) jsonBody(op apiOperation) map[string]interface{} {
+	body := b.schema(reflect.TypeOf(op.Response))
+	if len(op.Alternates) == 0 {
+		return body
+	}
+	variants := []interface{}{body}
+	for _, alt := range op.Alternates {
+		variants = append(variants, b.schema(reflect.TypeOf(alt)))
+	}
+	return map[string]interface{}{"oneOf": variants}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// buildOpenAPI assembles the OpenAPI 3 document for apiOperations.
+func buildOpenAPI() map[string]interface{} {
+	b := &schemaBuilder{components: make(map[string]interface{})}
+	problemRef := b.schema(reflect.TypeOf(problem{}))
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{
+				"application/problem+json": map[string]interface{}{"schema": problemRef},
+			},
+		}
+	}
+
+	paths := make(map[string]interface{})
+	for _, op := range apiOperations() {
+		params := make([]interface{}, 0, len(op.Params))
+		for _, p := range op.Params {
+			typ := p.Type
+			if typ == "" {
+				typ = "string"
+			}
+			schema := map[string]interface{}{"type": typ}
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"required":    p.In == "path",
+				"description": p.Description,
+				"schema":      schema,
+			})
+		}
+
+		var content map[string]interface{}
+		if op.MediaType != "" {
+			content = map[string]interface{}{op.MediaType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+		} else if op.Envelope {
+			content = jsonContent(map[string]interface{}{
+				"type":     "object",
+				"required": []string{"data", "meta", "links"},
+				"properties": map[string]interface{}{
+					"data":  b.jsonBody(op),
+					"meta":  b.schema(reflect.TypeOf(v2Meta{})),
+					"links": b.schema(reflect.TypeOf(v2Links{})),
+				},
+			})
+		} else {
+			content = jsonContent(b.jsonBody(op))
+		}
+		if op.Exports {
+			text := map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+			content["text/csv"] = text
+			content["application/x-ndjson"] = text
+		}
+		ok := map[string]interface{}{"description": "OK", "content": content}
+		if op.Paginated {
+			ok["headers"] = map[string]interface{}{
+				"X-Total-Count": map[string]interface{}{"description": "Number of matching results.", "schema": map[string]interface{}{"type": "integer"}},
+				"X-Next-Cursor": map[string]interface{}{"description": "Cursor of the next page, absent on the last page.", "schema": map[string]interface{}{"type": "string"}},
+				"Link":          map[string]interface{}{"description": `RFC 8288 link with rel="next".`, "schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+		responses := map[string]interface{}{
+			"200": ok,
+			"400": errorResponse("Invalid parameter"),
+			"405": map[string]interface{}{"description": "Method not allowed"},
+		}
+		if op.BadRequest != nil {
+			responses["400"] = map[string]interface{}{
+				"description": "Invalid request",
+				"content":     jsonContent(b.schema(reflect.TypeOf(op.BadRequest))),
+			}
+		}
+		if op.Redirects {
+			responses["301"] = map[string]interface{}{"description": "Permanent redirect to the canonical URL"}
+		}
+		if op.NotFound {
+			responses["404"] = errorResponse("Not found")
+		}
+		if op.Unavailable {
+			responses["503"] = errorResponse("Upstream data or integration unavailable")
+		}
+		method := strings.ToLower(op.Method)
+		if method == "" {
+			method = "get"
+		}
+		operation := map[string]interface{}{
+			"summary":     op.Summary,
+			"operationId": operationID(method, op.Path),
+			"parameters":  params,
+			"responses":   responses,
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(b.schema(reflect.TypeOf(op.Request))),
+			}
+		}
+		if op.Admin {
+			responses["401"] = errorResponse("Missing or invalid admin token")
+			operation["security"] = []interface{}{map[string]interface{}{"adminToken": []string{}}}
+		}
+		item, found := paths[op.Path].(map[string]interface{})
+		if !found {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+		item[method] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Groupie Tracker API",
+			"version":     "1.0.0",
+			"description": "Artists, members, locations and concerts from the Groupie Tracker API, with optional Spotify data.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.components,
+			"securitySchemes": map[string]interface{}{
+				"adminToken": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// operationID derives a camelCase ID from a method and a path, e.g.
+// get /api/artists/{slug} -> getArtistsBySlug.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(method)
+	for _, seg := range strings.Split(strings.TrimPrefix(path, "/api/"), "/") {
+		if strings.HasPrefix(seg, "{") {
+			b.WriteString("By")
+			seg = strings.Trim(seg, "{}")
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '.' || r == '_' }) {
+			r := []rune(word)
+			r[0] = unicode.ToUpper(r[0])
+			b.WriteString(string(r))
+		}
+	}
+	return b.String()
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  map[string]interface{}
+)
+
+func (a *App) handleAPIOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	openAPIOnce.Do(func() { openAPIDoc = buildOpenAPI() })
+	writeJSON(w, http.StatusOK, openAPIDoc)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func fetchOpenAPI(t *testing.T) map[string]interface{} {
+	t.Helper()
+	app := newTestApp()
+	rr := httptest.NewRecorder()
+	app.handleAPIOpenAPI(rr, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return doc
+}
+
+// TestOpenAPICoversRoutes fails when a registered API route is missing from
+// the spec, or when the spec documents a route that is not registered.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	if doc["openapi"] != "3.0.3" {
+		t.Fatalf("unexpected openapi version %v", doc["openapi"])
+	}
+	paths := doc["paths"].(map[string]interface{})
+
+	app := newTestApp()
+	routes := append(app.apiRoutes(), app.serviceRoutes()...)
+	for _, route := range routes {
+		found := false
+		for path := range paths {
+			if strings.HasSuffix(route.Pattern, "/") {
+				found = found || (strings.HasPrefix(path, route.Pattern) && strings.Contains(path, "{"))
+			} else {
+				found = found || path == route.Pattern
+			}
+		}
+		if !found {
+			t.Errorf("route %s is not documented in the OpenAPI spec", route.Pattern)
+		}
+	}
+	for path := range paths {
+		served := false
+		for _, route := range routes {
+			if strings.HasSuffix(route.Pattern, "/") && strings.Contains(path, "{") {
+				served = served || strings.HasPrefix(path, route.Pattern)
+			} else {
+				served = served || path == route.Pattern
+			}
+		}
+		if !served {
+			t.Errorf("spec path %s has no registered route", path)
+		}
+	}
+}
+
+func TestOpenAPIReferencesResolve(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"ArtistWithMeta", "Event", "Problem", "ViewLocation"} {
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("missing schema %s", name)
+		}
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := schemas[name]; !ok {
+					t.Errorf("unresolved reference %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	artist := schemas["ArtistWithMeta"].(map[string]interface{})["properties"].(map[string]interface{})
+	if locations := artist["locations"].(map[string]interface{}); locations["type"] != "array" {
+		t.Errorf("expected the artist's own locations list to shadow the embedded URL, got %v", locations)
+	}
+	if _, ok := artist["firstAlbumDate"]; !ok {
+		t.Error("expected embedded and direct fields to be merged")
+	}
+}
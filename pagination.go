@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPageLimit caps the page size a client may request.
+const maxPageLimit = 500
+
+// pageCursor is the decoded form of the opaque cursor parameter. It pins the
+// snapshot version and the query it was issued for, so a cursor can only be
+// replayed against the same data and filters.
+type pageCursor struct {
+	Version string `json:"v"`
+	Query   uint64 `json:"q"`
+	Offset  int    `json:"o"`
+	Limit   int    `json:"l"`
+}
+
+// pageRequest is a validated limit/cursor pair. Limit 0 means "everything".
+type pageRequest struct {
+	Limit   int
+	Offset  int
+	version string
+	query   uint64
+}
+
+// queryFingerprint hashes every parameter except the cursor itself.
+func queryFingerprint(q url.Values) uint64 {
+	clean := make(url.Values, len(q))
+	for k, v := range q {
+		if k != "cursor" {
+			clean[k] = v
+		}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(clean.Encode()))
+	return h.Sum64()
+}
+
+func encodeCursor(c pageCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// parsePage reads limit and cursor. A cursor issued for another snapshot
+// version or another query is rejected. Errors are *paramError.
+func parsePage(q url.Values, version string) (pageRequest, error) {
+	p := pageRequest{version: version, query: queryFingerprint(q)}
+	if raw := strings.TrimSpace(q.Get("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 || limit > maxPageLimit {
+			return p, newParamError("limit", codeInvalidLimit)
+		}
+		p.Limit = limit
+	}
+	raw := strings.TrimSpace(q.Get("cursor"))
+	if raw == "" {
+		return p, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	var c pageCursor
+	if err != nil || json.Unmarshal(data, &c) != nil || c.Offset < 0 {
+		return p, newParamError("cursor", codeInvalidCursor)
+	}
+	if c.Version != version {
+		return p, newParamError("cursor", codeCursorExpired)
+	}
+	if c.Query != p.query {
+		return p, newParamError("cursor", codeCursorMismatch)
+	}
+	p.Offset = c.Offset
+	if p.Limit == 0 {
+		p.Limit = c.Limit
+	}
+	return p, nil
+}
+
+// Bounds returns the slice bounds of the page and the cursor of the next page,
+// which is empty on the last page.
+func (p pageRequest) Bounds(total int) (int, int, string) {
+	start := p.Offset
+	if start > total {
+		start = total
+	}
+	if p.Limit == 0 {
+		return start, total, ""
+	}
+	end := start + p.Limit
+	if end >= total {
+		return start, total, ""
+	}
+	next := encodeCursor(pageCursor{Version: p.version, Query: p.query, Offset: end, Limit: p.Limit})
+	return start, end, next
+}
+
+// writePageHeaders exposes pagination metadata without changing list bodies:
+// X-Total-Count, X-Next-Cursor and an RFC 8288 next link.
+func writePageHeaders(w http.ResponseWriter, r *http.Request, total int, next string) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next == "" {
+		return
+	}
+	w.Header().Set("X-Next-Cursor", next)
+	q := r.URL.Query()
+	q.Set("cursor", next)
+	w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, q.Encode()))
+}
+
+// sortBy orders items in place by a named key, "-key" meaning descending.
+// An empty spec keeps the current order. The sort is stable so ties keep the
+// deterministic upstream order and cursors stay valid.
+func sortBy[T any](items []T, spec string, keys map[string]func(a, b T) bool) error {
+	if spec == "" {
+		return nil
+	}
+	desc := strings.HasPrefix(spec, "-")
+	less, ok := keys[strings.TrimPrefix(spec, "-")]
+	if !ok {
+		return fmt.Errorf("unknown sort key %q", spec)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+	return nil
+}
+
+var locationSortKeys = map[string]func(a, b viewLocation) bool{
+	"artistName": func(a, b viewLocation) bool { return normalizeText(a.ArtistName) < normalizeText(b.ArtistName) },
+	"city":       func(a, b viewLocation) bool { return normalizeText(a.City) < normalizeText(b.City) },
+	"country":    func(a, b viewLocation) bool { return normalizeText(a.Country) < normalizeText(b.Country) },
+	"eventCount": func(a, b viewLocation) bool { return a.EventCount < b.EventCount },
+}
+
+var eventSortKeys = map[string]func(a, b Event) bool{
+	"date":       func(a, b Event) bool { return a.Date.Before(b.Date) },
+	"artistName": func(a, b Event) bool { return normalizeText(a.ArtistName) < normalizeText(b.ArtistName) },
+	"city":       func(a, b Event) bool { return normalizeText(a.City) < normalizeText(b.City) },
+	"country":    func(a, b Event) bool { return normalizeText(a.Country) < normalizeText(b.Country) },
+}
+
+var relationSortKeys = map[string]func(a, b Relation) bool{
+	"id": func(a, b Relation) bool { return a.ID < b.ID },
+}
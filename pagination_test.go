@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func paginationTestApp() *App {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Alpha", CreationDate: 2000},
+			{ID: 2, Name: "Beta", CreationDate: 1990},
+			{ID: 3, Name: "Gamma", CreationDate: 2010},
+			{ID: 4, Name: "Delta", CreationDate: 1980},
+			{ID: 5, Name: "Epsilon", CreationDate: 1970},
+		},
+	})
+	return app
+}
+
+func getArtistNames(t *testing.T, app *App, target string) ([]string, http.Header) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d (%s)", target, rr.Code, rr.Body.String())
+	}
+	var got []ArtistWithMeta
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	names := make([]string, len(got))
+	for i, art := range got {
+		names[i] = art.Name
+	}
+	return names, rr.Header()
+}
+
+func TestArtistsCursorPagination(t *testing.T) {
+	app := paginationTestApp()
+
+	var all []string
+	target := "/api/artists?sort=name&limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		names, h := getArtistNames(t, app, target)
+		if h.Get("X-Total-Count") != "5" {
+			t.Fatalf("expected X-Total-Count 5, got %q", h.Get("X-Total-Count"))
+		}
+		all = append(all, names...)
+		target = ""
+		if next := h.Get("X-Next-Cursor"); next != "" {
+			if !strings.Contains(h.Get("Link"), `rel="next"`) {
+				t.Fatalf("expected Link header, got %q", h.Get("Link"))
+			}
+			target = "/api/artists?sort=name&limit=2&cursor=" + url.QueryEscape(next)
+		}
+	}
+	want := "Alpha,Beta,Delta,Epsilon,Gamma"
+	if strings.Join(all, ",") != want {
+		t.Fatalf("expected %s, got %v", want, all)
+	}
+}
+
+func TestArtistsWithoutLimitReturnsEverything(t *testing.T) {
+	app := paginationTestApp()
+	names, h := getArtistNames(t, app, "/api/artists?sort=-creationDate")
+	if len(names) != 5 || names[0] != "Gamma" {
+		t.Fatalf("unexpected result %v", names)
+	}
+	if h.Get("X-Next-Cursor") != "" {
+		t.Fatalf("expected no next cursor, got %q", h.Get("X-Next-Cursor"))
+	}
+}
+
+func TestCursorRejectedAfterRefreshOrQueryChange(t *testing.T) {
+	app := paginationTestApp()
+	_, h := getArtistNames(t, app, "/api/artists?sort=name&limit=2")
+	cursor := url.QueryEscape(h.Get("X-Next-Cursor"))
+
+	cases := []string{
+		"/api/artists?sort=-name&limit=2&cursor=" + cursor,
+		"/api/artists?cursor=not-a-cursor",
+	}
+	for _, target := range cases {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		app.handleAPIArtists(rr, req)
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"param":"cursor"`) {
+			t.Fatalf("GET %s: expected cursor error, got %d %s", target, rr.Code, rr.Body.String())
+		}
+	}
+
+	app.cache.Set(DataBundle{Artists: []Artist{{ID: 9, Name: "Zeta"}}})
+	req := httptest.NewRequest(http.MethodGet, "/api/artists?sort=name&limit=2&cursor="+cursor, nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIArtists(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected stale cursor to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestEventsSortAndInvalidSort(t *testing.T) {
+	app := newTestApp()
+	app.cache.Set(DataBundle{
+		Artists: []Artist{{ID: 1, Name: "Zed"}, {ID: 2, Name: "Abba"}},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{"paris-france": {"01-01-2020"}}},
+			{ID: 2, DatesLocations: map[string][]string{"berlin-germany": {"01-01-2021"}}},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events?sort=artistName&limit=1", nil)
+	rr := httptest.NewRecorder()
+	app.handleAPIEvents(rr, req)
+	var events []Event
+	if err := json.Unmarshal(rr.Body.Bytes(), &events); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(events) != 1 || events[0].ArtistName != "Abba" {
+		t.Fatalf("expected Abba first, got %+v", events)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/locations?sort=altitude", nil)
+	rr = httptest.NewRecorder()
+	app.handleAPILocations(rr, req)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"param":"sort"`) {
+		t.Fatalf("expected sort error, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Stable error codes. Clients may rely on them; messages may change.
+const (
+	codeInvalidParameter  = "invalid_parameter"
+	codeInvalidYear       = "invalid_year"
+	codeInvalidThreshold  = "invalid_threshold"
+	codeInvalidTimeZone   = "invalid_time_zone"
+	codeInvalidWindow     = "invalid_window"
+	codeInvalidOffset     = "invalid_offset"
+	codeInvalidLimit      = "invalid_limit"
+	codeLimitOutOfRange   = "limit_out_of_range"
+	codeInvalidCursor     = "invalid_cursor"
+	codeCursorExpired     = "cursor_expired"
+	codeCursorMismatch    = "cursor_mismatch"
+	codeCursorWithSpotify = "cursor_with_spotify"
+	codeInvalidSort       = "invalid_sort"
+	codeInvalidFacet      = "invalid_facet"
+	codeInvalidSource     = "invalid_source"
+	codeInvalidBandCount  = "invalid_band_count"
+	codeInvalidFormat     = "invalid_format"
+	codeFacetsJSONOnly    = "facets_json_only"
+	codeIncludeJSONOnly   = "include_json_only"
+	codeUnknownField      = "unknown_field"
+	codeUnknownInclude    = "unknown_include"
+	codeUnknownArtist     = "unknown_artist"
+	codeInvalidEventType  = "invalid_event_type"
+	codeInvalidRange      = "invalid_range"
+	codeInvalidDateRange  = "invalid_date_range"
+	codeInvalidInteger    = "invalid_integer"
+	codeInvalidDate       = "invalid_date"
+	codeInvalidID         = "invalid_id"
+	codeInvalidArtistID   = "invalid_artist_id"
+	codeInvalidIDs        = "invalid_ids"
+	codeInvalidResultType = "invalid_result_type"
+	codeMissingID         = "missing_id"
+	codeInvalidQuery      = "invalid_query"
+	// Reasons of an invalid_query problem, one per parse error.
+	codeQueryUnclosedQuote    = "query_unclosed_quote"
+	codeQueryDanglingNegation = "query_dangling_negation"
+	codeQueryMissingField     = "query_missing_field"
+	codeQueryUnknownField     = "query_unknown_field"
+	codeQueryInvalidOperator  = "query_invalid_operator"
+	codeQueryTextOperator     = "query_text_operator"
+	codeQueryMissingValue     = "query_missing_value"
+	codeQueryExpectedNumber   = "query_expected_number"
+	codeQueryExpectedDate     = "query_expected_date"
+	codeInvalidBody           = "invalid_body"
+	codeBodyTooLarge          = "body_too_large"
+	codeInvalidBatchSize      = "invalid_batch_size"
+	codeInvalidBatchPath      = "invalid_batch_path"
+	codeNotFound              = "not_found"
+	codeArtistNotFound        = "artist_not_found"
+	codeMemberNotFound        = "member_not_found"
+	codeEventNotFound         = "event_not_found"
+	codeLocationNotFound      = "location_not_found"
+	codeNotInV2               = "not_in_v2"
+	codeCountryNotFound       = "country_not_found"
+	codeCityNotFound          = "city_not_found"
+	codeAdminDisabled         = "admin_disabled"
+	codeAdminUnauthorized     = "admin_unauthorized"
+	codeMethodNotAllowed      = "method_not_allowed"
+	codeInternal              = "internal_error"
+	codeSpotifyUnavailable    = "spotify_unavailable"
+	codeSpotifyDisabled       = "spotify_disabled"
+	codeUnavailable           = "service_unavailable"
+)
+
+// Supported message languages; the first one is the default.
+var problemLanguages = []string{"fr", "en"}
+
+// problemSpec describes an error code. Detail templates take the arguments
+// given at the call site; a missing Detail reuses the title.
+type problemSpec struct {
+	Status int
+	Title  map[string]string
+	Detail map[string]string
+}
+
+func spec(status int, fr, en string) problemSpec {
+	return problemSpec{Status: status, Title: map[string]string{"fr": fr, "en": en}}
+}
+
+func (s problemSpec) withDetail(fr, en string) problemSpec {
+	s.Detail = map[string]string{"fr": fr, "en": en}
+	return s
+}
+
+var problemCatalog = map[string]problemSpec{
+	codeInvalidParameter: spec(http.StatusBadRequest, "paramètre invalide", "invalid parameter"),
+	codeInvalidYear:      spec(http.StatusBadRequest, "année invalide", "invalid year"),
+	codeInvalidThreshold: spec(http.StatusBadRequest, "seuil de similarité invalide", "invalid similarity threshold"),
+	codeInvalidTimeZone:  spec(http.StatusBadRequest, "fuseau horaire invalide", "invalid time zone"),
+	codeInvalidWindow:    spec(http.StatusBadRequest, "fenêtre temporelle invalide", "invalid time window"),
+	codeInvalidOffset:    spec(http.StatusBadRequest, "décalage invalide", "invalid offset"),
+	codeInvalidLimit:     spec(http.StatusBadRequest, "limite invalide", "invalid limit"),
+	codeLimitOutOfRange: spec(http.StatusBadRequest, "limite invalide", "invalid limit").
+		withDetail("la limite doit être comprise entre 1 et %d", "limit must be between 1 and %d"),
+	codeInvalidCursor:  spec(http.StatusBadRequest, "curseur invalide", "invalid cursor"),
+	codeCursorExpired:  spec(http.StatusBadRequest, "curseur expiré : les données ont été rafraîchies", "cursor expired: the data has been refreshed"),
+	codeCursorMismatch: spec(http.StatusBadRequest, "curseur émis pour une autre requête", "cursor was issued for another query"),
+	codeCursorWithSpotify: spec(http.StatusBadRequest, "pagination indisponible avec Spotify", "pagination is not available with Spotify").
+		withDetail("les résultats Spotify ne tiennent que sur une page ; utilisez source=groupie pour paginer", "Spotify results only come as one page; use source=groupie to paginate"),
+	codeInvalidSort: spec(http.StatusBadRequest, "tri invalide", "invalid sort"),
+	codeInvalidFacet: spec(http.StatusBadRequest, "facette invalide", "invalid facet").
+		withDetail("facette inconnue « %s »", "unknown facet %q"),
+	codeInvalidSource:    spec(http.StatusBadRequest, "source invalide", "invalid source"),
+	codeInvalidBandCount: spec(http.StatusBadRequest, "nombre de groupes invalide", "invalid number of bands"),
+	codeInvalidFormat:    spec(http.StatusBadRequest, "format invalide (json, csv ou ndjson)", "invalid format (json, csv or ndjson)"),
+	codeFacetsJSONOnly:   spec(http.StatusBadRequest, "les facettes ne sont disponibles qu'en JSON", "facets are only available in JSON"),
+	codeIncludeJSONOnly:  spec(http.StatusBadRequest, "include n'est pas disponible en CSV", "include is not available in CSV"),
+	codeUnknownField: spec(http.StatusBadRequest, "champ inconnu", "unknown field").
+		withDetail("champ inconnu « %s »", "unknown field %q"),
+	codeUnknownInclude: spec(http.StatusBadRequest, "ressource liée inconnue", "unknown related resource").
+		withDetail("ressource liée inconnue « %s »", "unknown related resource %q"),
+	codeUnknownArtist: spec(http.StatusBadRequest, "artiste inconnu", "unknown artist").
+		withDetail("artiste inconnu « %s »", "unknown artist %q"),
+	codeInvalidEventType: spec(http.StatusBadRequest, "type d'événement invalide", "invalid event type").
+		withDetail("type d'événement inconnu « %s »", "unknown event type %q"),
+	codeInvalidRange: spec(http.StatusBadRequest, "plage invalide", "invalid range").
+		withDetail("plage invalide : %s (%d) est supérieur à %s (%d)", "invalid range: %s (%d) is greater than %s (%d)"),
+	codeInvalidDateRange: spec(http.StatusBadRequest, "plage invalide", "invalid range").
+		withDetail("plage invalide : %s (%s) est postérieur à %s (%s)", "invalid range: %s (%s) is after %s (%s)"),
+	codeInvalidInteger: spec(http.StatusBadRequest, "entier invalide", "invalid integer").
+		withDetail("%s doit être un entier positif, reçu %q", "%s must be a non-negative integer, got %q"),
+	codeInvalidDate: spec(http.StatusBadRequest, "date invalide", "invalid date").
+		withDetail("%s doit être une date au format AAAA-MM-JJ, reçu %q", "%s must be a date formatted YYYY-MM-DD, got %q"),
+	codeInvalidID:       spec(http.StatusBadRequest, "identifiant invalide", "invalid identifier"),
+	codeInvalidArtistID: spec(http.StatusBadRequest, "identifiant d'artiste invalide", "invalid artist identifier"),
+	codeInvalidIDs: spec(http.StatusBadRequest, "liste d'identifiants invalide", "invalid identifier list").
+		withDetail("ids doit être une liste d'au plus %d identifiants séparés par des virgules", "ids must be a comma list of at most %d identifiers"),
+	codeInvalidResultType: spec(http.StatusBadRequest, "type de résultat invalide", "invalid result type"),
+	codeMissingID:         spec(http.StatusBadRequest, "l'identifiant est requis", "id is required"),
+	codeInvalidQuery: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("position %d : %s", "invalid query at position %d: %s"),
+	codeQueryUnclosedQuote: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("guillemet non fermé", "unclosed quote"),
+	codeQueryDanglingNegation: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("« - » doit précéder un terme", `"-" must precede a term`),
+	codeQueryMissingField: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("champ manquant avant l'opérateur", "missing field before the operator"),
+	codeQueryUnknownField: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("champ inconnu « %s » ; mettez le texte entre guillemets s'il ne s'agit pas d'un champ", "unknown field %q; quote the text if it is not a field"),
+	codeQueryInvalidOperator: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("opérateur invalide", "invalid operator"),
+	codeQueryTextOperator: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("l'opérateur %s ne s'applique pas au champ texte « %s »", "operator %s does not apply to text field %q"),
+	codeQueryMissingValue: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("valeur manquante pour « %s »", "missing value for %q"),
+	codeQueryExpectedNumber: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("nombre attendu pour « %s », reçu « %s »", "%q expects a number, got %q"),
+	codeQueryExpectedDate: spec(http.StatusBadRequest, "requête invalide", "invalid query").
+		withDetail("date AAAA-MM-JJ attendue pour « %s », reçu « %s »", "%q expects a YYYY-MM-DD date, got %q"),
+	codeInvalidBody: spec(http.StatusBadRequest, "corps JSON invalide", "invalid JSON body"),
+	codeBodyTooLarge: spec(http.StatusRequestEntityTooLarge, "corps trop volumineux", "body too large").
+		withDetail("le corps dépasse %d octets", "the body exceeds %d bytes"),
+	codeInvalidBatchSize: spec(http.StatusBadRequest, "nombre de requêtes invalide", "invalid number of requests").
+		withDetail("un lot contient de 1 à %d requêtes", "a batch holds 1 to %d requests"),
+	codeInvalidBatchPath: spec(http.StatusBadRequest, "chemin invalide", "invalid path").
+		withDetail("seules les routes GET sous /api/ sont disponibles dans un lot", "only GET routes under /api/ can be batched"),
+	codeNotFound:         spec(http.StatusNotFound, "page introuvable", "not found"),
+	codeArtistNotFound:   spec(http.StatusNotFound, "artiste introuvable", "artist not found"),
+	codeMemberNotFound:   spec(http.StatusNotFound, "membre introuvable", "member not found"),
+	codeEventNotFound:    spec(http.StatusNotFound, "concert introuvable", "event not found"),
+	codeLocationNotFound: spec(http.StatusNotFound, "lieu introuvable", "location not found"),
+	codeNotInV2: spec(http.StatusNotFound, "ressource absente de la v2", "resource not in v2").
+		withDetail("%s n'existe pas en v2 ; utilisez %s", "%s does not exist in v2; use %s"),
+	codeCountryNotFound:    spec(http.StatusNotFound, "pays introuvable", "country not found"),
+	codeCityNotFound:       spec(http.StatusNotFound, "ville introuvable", "city not found"),
+	codeAdminDisabled:      spec(http.StatusNotFound, "administration désactivée", "administration is disabled"),
+	codeAdminUnauthorized:  spec(http.StatusUnauthorized, "jeton d'administration invalide", "invalid admin token"),
+	codeMethodNotAllowed:   spec(http.StatusMethodNotAllowed, "méthode non autorisée", "method not allowed"),
+	codeInternal:           spec(http.StatusInternalServerError, "erreur interne", "internal error"),
+	codeSpotifyUnavailable: spec(http.StatusBadGateway, "spotify indisponible", "Spotify is unavailable"),
+	codeSpotifyDisabled:    spec(http.StatusServiceUnavailable, "l'intégration Spotify n'est pas configurée", "the Spotify integration is not configured"),
+	codeUnavailable:        spec(http.StatusServiceUnavailable, "service indisponible", "service unavailable"),
+}
+
+// problemText returns the title and detail of a code in a language.
+func problemText(lang, code string, args ...interface{}) (string, string) {
+	s, ok := problemCatalog[code]
+	if !ok {
+		s = problemCatalog[codeInternal]
+	}
+	title := s.Title[lang]
+	if s.Detail == nil {
+		return title, title
+	}
+	return title, fmt.Sprintf(s.Detail[lang], args...)
+}
+
+// problemMessage is the default-language detail of a code, used where no
+// request is at hand (error strings, GraphQL).
+func problemMessage(code string, args ...interface{}) string {
+	_, detail := problemText(problemLanguages[0], code, args...)
+	return detail
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	Param    string `json:"param,omitempty"`
+	Position *int   `json:"position,omitempty"`
+	// Reason is the query_* code of an invalid_query problem.
+	Reason string `json:"reason,omitempty"`
+	// Error repeats Detail for v1 clients that read {"error": ...}.
+	Error string `json:"error,omitempty"`
+}
+
+// problemType is the type URI of a code.
+func problemType(code string) string {
+	return "urn:groupie-tracker:problem:" + code
+}
+
+// newProblem builds the problem for a code in the request's language.
+func newProblem(r *http.Request, code string, args ...interface{}) problem {
+	lang := preferredLanguage(r)
+	title, detail := problemText(lang, code, args...)
+	status := http.StatusInternalServerError
+	if s, ok := problemCatalog[code]; ok {
+		status = s.Status
+	}
+	p := problem{
+		Type:     problemType(code),
+		Title:    title,
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+	if !strings.HasPrefix(r.URL.Path, apiV2Prefix+"/") {
+		p.Error = detail
+	}
+	return p
+}
+
+// writeProblem sends the problem for a code as application/problem+json.
+func writeProblem(w http.ResponseWriter, r *http.Request, code string, args ...interface{}) {
+	sendProblem(w, r, newProblem(r, code, args...))
+}
+
+// sendProblem writes p as application/problem+json in the request's language.
+func sendProblem(w http.ResponseWriter, r *http.Request, p problem) {
+	h := w.Header()
+	h.Set("Content-Type", "application/problem+json; charset=utf-8")
+	h.Set("Content-Language", preferredLanguage(r))
+	h.Add("Vary", "Accept-Language")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Printf("encode problem: %v", err)
+	}
+}
+
+// writeParamError reports a rejected query parameter as a 400 problem naming
+// the parameter.
+func writeParamError(w http.ResponseWriter, r *http.Request, err error) {
+	var pe *paramError
+	if !errors.As(err, &pe) {
+		pe = &paramError{Code: codeInvalidParameter}
+	}
+	p := newProblem(r, pe.Code, pe.Args...)
+	p.Param = pe.Param
+	sendProblem(w, r, p)
+}
+
+// preferredLanguage picks the best supported language from Accept-Language.
+func preferredLanguage(r *http.Request) string {
+	best, bestQ := problemLanguages[0], 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, q := parseQualified(part)
+		lang := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		for _, supported := range problemLanguages {
+			if lang == supported && q > bestQ {
+				best, bestQ = supported, q
+			}
+		}
+	}
+	return best
+}
+
+// parseQualified splits "value;q=0.5" into the value and its quality.
+func parseQualified(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	q := 1.0
+	for _, param := range fields[1:] {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return "", 0
+			}
+			q = parsed
+		}
+	}
+	return strings.TrimSpace(fields[0]), q
+}
+
+// prefersHTML reports whether the Accept header ranks HTML above JSON. On a
+// tie, as with */* alone or no header at all, pages get HTML and the API
+// gets JSON.
+func prefersHTML(r *http.Request) bool {
+	var htmlQ, jsonQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			if q > htmlQ {
+				htmlQ = q
+			}
+		case "application/json", "application/problem+json":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		}
+	}
+	if htmlQ != jsonQ {
+		return htmlQ > jsonQ
+	}
+	return !isAPIPath(r.URL.Path)
+}
+
+// isAPIPath reports whether a path belongs to the JSON API.
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/") || path == "/api" || path == "/graphql"
+}
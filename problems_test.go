@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveProblem(t *testing.T, app *App, method, target string, header map[string]string) (*httptest.ResponseRecorder, problem) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	var p problem
+	if strings.HasPrefix(rr.Header().Get("Content-Type"), "application/problem+json") {
+		if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: decode %q: %v", target, rr.Body.String(), err)
+		}
+	}
+	return rr, p
+}
+
+func TestParamErrorProblem(t *testing.T) {
+	app := graphQLTestApp()
+	rr, p := serveProblem(t, app, http.MethodGet, "/api/artists?creation_from=2005&creation_to=2000", nil)
+	if rr.Code != http.StatusBadRequest || p.Status != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %+v", rr.Code, p)
+	}
+	if p.Code != codeInvalidRange || p.Param != "creation_from" || p.Type != problemType(codeInvalidRange) || p.Instance != "/api/artists" {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+	want := "plage invalide : creation_from (2005) est supérieur à creation_to (2000)"
+	if p.Detail != want || p.Error != want {
+		t.Fatalf("detail %q, error %q", p.Detail, p.Error)
+	}
+	if rr.Header().Get("Content-Language") != "fr" {
+		t.Fatalf("unexpected Content-Language %q", rr.Header().Get("Content-Language"))
+	}
+}
+
+func TestParamErrorsNameTheParameter(t *testing.T) {
+	app := graphQLTestApp()
+	cases := []struct{ target, code, param string }{
+		{"/api/artists?facets=bogus", codeInvalidFacet, "facets"},
+		{"/api/events?facets=bogus", codeInvalidFacet, "facets"},
+		{"/api/artists?spotify_limit=-1", codeInvalidLimit, "spotify_limit"},
+		{"/api/dates?year=soon", codeInvalidYear, "year"},
+		{"/api/relation?id=x", codeInvalidID, "id"},
+		{"/api/members?min_bands=-1", codeInvalidBandCount, "min_bands"},
+		{"/api/search?q=queen&type=album", codeInvalidResultType, "type"},
+		{"/api/search?q=queen&limit=101", codeLimitOutOfRange, "limit"},
+		{"/api/suggest?q=qu&limit=21", codeLimitOutOfRange, "limit"},
+	}
+	for _, tc := range cases {
+		rr, p := serveProblem(t, app, http.MethodGet, tc.target, nil)
+		if rr.Code != http.StatusBadRequest || p.Code != tc.code || p.Param != tc.param {
+			t.Errorf("%s: unexpected %d %+v", tc.target, rr.Code, p)
+		}
+	}
+	_, p := serveProblem(t, app, http.MethodGet, "/api/artists?facets=bogus", map[string]string{"Accept-Language": "en"})
+	if p.Detail != `unknown facet "bogus"` {
+		t.Fatalf("unexpected detail %q", p.Detail)
+	}
+}
+
+func TestProblemLocalized(t *testing.T) {
+	app := graphQLTestApp()
+	rr, p := serveProblem(t, app, http.MethodGet, "/api/events?date_from=soon", map[string]string{"Accept-Language": "de, en-GB;q=0.8, fr;q=0.5"})
+	if rr.Header().Get("Content-Language") != "en" || p.Title != "invalid date" {
+		t.Fatalf("expected English, got %q %+v", rr.Header().Get("Content-Language"), p)
+	}
+	if p.Detail != `date_from must be a date formatted YYYY-MM-DD, got "soon"` || p.Code != codeInvalidDate {
+		t.Fatalf("unexpected detail %q", p.Detail)
+	}
+	if !strings.Contains(rr.Header().Get("Vary"), "Accept-Language") {
+		t.Fatalf("missing Vary: %v", rr.Header()["Vary"])
+	}
+}
+
+func TestQueryProblemPosition(t *testing.T) {
+	app := graphQLTestApp()
+	_, p := serveProblem(t, app, http.MethodGet, "/api/query?q=year%3E", map[string]string{"Accept-Language": "en"})
+	if p.Code != codeInvalidQuery || p.Reason != codeQueryMissingValue || p.Param != "q" || p.Position == nil || *p.Position != 5 {
+		t.Fatalf("unexpected problem %+v", p)
+	}
+	if p.Detail != `invalid query at position 5: missing value for "year"` {
+		t.Fatalf("unexpected detail %q", p.Detail)
+	}
+
+	_, p = serveProblem(t, app, http.MethodGet, "/api/query?q=year%3E", nil)
+	if p.Detail != "position 5 : valeur manquante pour « year »" {
+		t.Fatalf("unexpected French detail %q", p.Detail)
+	}
+}
+
+func TestErrorContentNegotiation(t *testing.T) {
+	app := graphQLTestApp()
+	cases := []struct {
+		target, accept string
+		html           bool
+	}{
+		{"/api/nowhere", "", false},
+		{"/api/nowhere", "*/*", false},
+		{"/api/nowhere", "text/html,application/xhtml+xml,*/*;q=0.8", true},
+		{"/nowhere", "", true},
+		{"/nowhere", "application/json", false},
+	}
+	for _, tc := range cases {
+		rr, p := serveProblem(t, app, http.MethodGet, tc.target, map[string]string{"Accept": tc.accept})
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("%s (%s): expected 404, got %d", tc.target, tc.accept, rr.Code)
+		}
+		html := strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html")
+		if html != tc.html {
+			t.Errorf("%s (%s): html=%v, got %s", tc.target, tc.accept, html, rr.Header().Get("Content-Type"))
+		}
+		if !tc.html && p.Code != codeNotFound {
+			t.Errorf("%s (%s): unexpected problem %+v", tc.target, tc.accept, p)
+		}
+	}
+}
+
+func TestMethodNotAllowedProblem(t *testing.T) {
+	app := graphQLTestApp()
+	rr, p := serveProblem(t, app, http.MethodDelete, "/api/artists", nil)
+	if rr.Code != http.StatusMethodNotAllowed || p.Code != codeMethodNotAllowed || rr.Header().Get("Allow") != http.MethodGet {
+		t.Fatalf("unexpected %d %+v (Allow %q)", rr.Code, p, rr.Header().Get("Allow"))
+	}
+	rr, _ = serveProblem(t, app, http.MethodDelete, "/graphql", nil)
+	if rr.Header().Get("Allow") != "GET, POST" {
+		t.Fatalf("GraphQL Allow header lost: %q", rr.Header().Get("Allow"))
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                      "fr",
+		"en":                    "en",
+		"en-US,en;q=0.9":        "en",
+		"fr-CA, en;q=0.9":       "fr",
+		"en;q=0.2, fr-FR;q=0.7": "fr",
+		"de, es":                "fr",
+		"en;q=0":                "fr",
+	}
+	for header, want := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/artists", nil)
+		req.Header.Set("Accept-Language", header)
+		if got := preferredLanguage(req); got != want {
+			t.Errorf("%q: got %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestProblemCatalogComplete(t *testing.T) {
+	for code, s := range problemCatalog {
+		for _, lang := range problemLanguages {
+			if s.Title[lang] == "" {
+				t.Errorf("%s: missing %s title", code, lang)
+			}
+			if s.Detail != nil && s.Detail[lang] == "" {
+				t.Errorf("%s: missing %s detail", code, lang)
+			}
+		}
+	}
+}
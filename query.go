@@ -0,0 +1,369 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The query language combines whitespace separated terms with AND:
+//
+//	member:freddie country:uk year>=1980 -city:london "pink floyd"
+//
+// A term is either free text (a word or a quoted phrase) or field, operator and
+// value. A leading "-" negates the term. Terms are evaluated against rows that
+// join an artist with one of its concerts, so every positive location or date
+// condition has to hold for the same concert.
+
+// Field kinds decide which operators a field accepts.
+const (
+	kindText = iota
+	kindNumber
+	kindDate
+)
+
+var queryFields = map[string]int{
+	"name":     kindText,
+	"artist":   kindText,
+	"member":   kindText,
+	"city":     kindText,
+	"country":  kindText,
+	"year":     kindNumber,
+	"created":  kindNumber,
+	"creation": kindNumber,
+	"album":    kindNumber,
+	"members":  kindNumber,
+	"date":     kindDate,
+}
+
+// queryOps lists operators longest first so ">=" wins over ">".
+var queryOps = []string{">=", "<=", "!=", ":", "=", ">", "<"}
+
+// queryTerm is one parsed condition. Field is empty for free text.
+type queryTerm struct {
+	Field  string
+	Op     string
+	Value  string
+	Negate bool
+	Pos    int
+
+	number int
+	date   time.Time
+}
+
+// queryError reports a parse error at a character offset in the query.
+// Code is one of the query_* entries of problemCatalog and Args fill its
+// detail template.
+type queryError struct {
+	Pos  int
+	Code string
+	Args []interface{}
+}
+
+func newQueryError(pos int, code string, args ...interface{}) *queryError {
+	return &queryError{Pos: pos, Code: code, Args: args}
+}
+
+// Error returns the message in the default language.
+func (e *queryError) Error() string {
+	return problemMessage(codeInvalidQuery, e.Pos, problemMessage(e.Code, e.Args...))
+}
+
+// queryScanner walks the query rune by rune while tracking character offsets.
+type queryScanner struct {
+	src string
+	off int // byte offset
+	pos int // character offset
+}
+
+func (s *queryScanner) peek() rune {
+	if s.off >= len(s.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(s.src[s.off:])
+	return r
+}
+
+func (s *queryScanner) next() rune {
+	r, size := utf8.DecodeRuneInString(s.src[s.off:])
+	s.off += size
+	s.pos++
+	return r
+}
+
+func (s *queryScanner) done() bool {
+	return s.off >= len(s.src)
+}
+
+func isQueryOpStart(r rune) bool {
+	return r == ':' || r == '=' || r == '<' || r == '>' || r == '!'
+}
+
+// readQuoted reads a phrase after its opening quote.
+func (s *queryScanner) readQuoted(start int) (string, error) {
+	var b strings.Builder
+	for !s.done() {
+		r := s.next()
+		if r == '"' {
+			return b.String(), nil
+		}
+		b.WriteRune(r)
+	}
+	return "", newQueryError(start, codeQueryUnclosedQuote)
+}
+
+// readUntil reads runes until whitespace or, when stopAtOp is set, an operator.
+func (s *queryScanner) readUntil(stopAtOp bool) string {
+	start := s.off
+	for !s.done() {
+		r := s.peek()
+		if unicode.IsSpace(r) || (stopAtOp && isQueryOpStart(r)) {
+			break
+		}
+		s.next()
+	}
+	return s.src[start:s.off]
+}
+
+// parseQuery parses a query string into terms. Errors are *queryError.
+func parseQuery(src string) ([]queryTerm, error) {
+	s := &queryScanner{src: src}
+	terms := make([]queryTerm, 0)
+	for {
+		for !s.done() && unicode.IsSpace(s.peek()) {
+			s.next()
+		}
+		if s.done() {
+			return terms, nil
+		}
+		term, err := s.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+}
+
+func (s *queryScanner) parseTerm() (queryTerm, error) {
+	term := queryTerm{Pos: s.pos}
+	if s.peek() == '-' {
+		s.next()
+		term.Negate = true
+		if s.done() || unicode.IsSpace(s.peek()) {
+			return term, newQueryError(term.Pos, codeQueryDanglingNegation)
+		}
+	}
+
+	if s.peek() == '"' {
+		start := s.pos
+		s.next()
+		phrase, err := s.readQuoted(start)
+		if err != nil {
+			return term, err
+		}
+		term.Value = phrase
+		return term, nil
+	}
+
+	fieldPos := s.pos
+	word := s.readUntil(true)
+	if s.done() || !isQueryOpStart(s.peek()) {
+		// Free text: keep the rest of the word, including any punctuation.
+		term.Value = word
+		return term, nil
+	}
+
+	opPos := s.pos
+	rest := s.src[s.off:]
+	for _, op := range queryOps {
+		if strings.HasPrefix(rest, op) {
+			term.Op = op
+			break
+		}
+	}
+
+	field := strings.ToLower(word)
+	kind, ok := queryFields[field]
+	if !ok {
+		if word == "" {
+			return term, newQueryError(fieldPos, codeQueryMissingField)
+		}
+		if after := rest[len(term.Op):]; term.Op == "" || after == "" || unicode.IsSpace([]rune(after)[0]) {
+			// Not a field condition: punctuation belongs to the word ("Wham!").
+			term.Op = ""
+			term.Value = word + s.readUntil(false)
+			return term, nil
+		}
+		return term, newQueryError(fieldPos, codeQueryUnknownField, word)
+	}
+	term.Field = field
+
+	if term.Op == "" {
+		return term, newQueryError(opPos, codeQueryInvalidOperator)
+	}
+	for range term.Op {
+		s.next()
+	}
+	if kind == kindText && term.Op != ":" && term.Op != "=" && term.Op != "!=" {
+		return term, newQueryError(opPos, codeQueryTextOperator, term.Op, field)
+	}
+
+	valuePos := s.pos
+	if s.peek() == '"' {
+		s.next()
+		value, err := s.readQuoted(valuePos)
+		if err != nil {
+			return term, err
+		}
+		term.Value = value
+	} else {
+		term.Value = s.readUntil(false)
+	}
+	if strings.TrimSpace(term.Value) == "" {
+		return term, newQueryError(valuePos, codeQueryMissingValue, field)
+	}
+
+	switch kind {
+	case kindNumber:
+		n, err := strconv.Atoi(term.Value)
+		if err != nil {
+			return term, newQueryError(valuePos, codeQueryExpectedNumber, field, term.Value)
+		}
+		term.number = n
+	case kindDate:
+		d, err := time.Parse("2006-01-02", term.Value)
+		if err != nil {
+			return term, newQueryError(valuePos, codeQueryExpectedDate, field, term.Value)
+		}
+		term.date = d
+	}
+	return term, nil
+}
+
+// queryRow joins an artist with one of its concerts. Event is nil for artists
+// without concerts; positive location and date conditions never hold for such rows.
+type queryRow struct {
+	Artist *ArtistWithMeta
+	Event  *Event
+}
+
+func compareInts(op string, got, want int) bool {
+	switch op {
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	}
+	return got == want
+}
+
+// matchText compares text fields: "=" is an exact (normalised) match, ":" a substring match.
+func matchText(op, got, want string) bool {
+	if op == "=" {
+		return normalizeText(got) == normalizeText(want)
+	}
+	return textContains(got, want)
+}
+
+// test evaluates the term without its negation. "!=" is the negation of "=".
+func (t queryTerm) test(row queryRow) bool {
+	if t.Op == "!=" {
+		eq := t
+		eq.Op = "="
+		return !eq.test(row)
+	}
+	art, ev := row.Artist, row.Event
+	switch t.Field {
+	case "":
+		if textContains(art.Name, t.Value) || containsMember(art.Members, t.Value) {
+			return true
+		}
+		return ev != nil && (textContains(ev.City, t.Value) || textContains(ev.Country, t.Value))
+	case "name", "artist":
+		return matchText(t.Op, art.Name, t.Value)
+	case "member":
+		for _, m := range art.Members {
+			if matchText(t.Op, m, t.Value) {
+				return true
+			}
+		}
+		return false
+	case "city":
+		return ev != nil && matchText(t.Op, ev.City, t.Value)
+	case "country":
+		return ev != nil && (matchText(t.Op, ev.Country, t.Value) || matchText(t.Op, countryCode(ev.Location), t.Value))
+	case "year":
+		return ev != nil && compareInts(t.Op, ev.Date.Year(), t.number)
+	case "date":
+		return ev != nil && compareInts(t.Op, int(ev.Date.Sub(t.date).Hours()/24), 0)
+	case "created", "creation":
+		return art.CreationDate > 0 && compareInts(t.Op, art.CreationDate, t.number)
+	case "album":
+		return art.HasFirstAlbum() && compareInts(t.Op, art.FirstAlbumYear, t.number)
+	case "members":
+		return compareInts(t.Op, len(art.Members), t.number)
+	}
+	return false
+}
+
+// Matches reports whether the row satisfies the term, honouring negation.
+func (t queryTerm) Matches(row queryRow) bool {
+	return t.test(row) != t.Negate
+}
+
+// queryResult lists the artists and concerts selected by a query.
+type queryResult struct {
+	Query   string           `json:"query"`
+	Artists []ArtistWithMeta `json:"artists"`
+	Events  []Event          `json:"events"`
+}
+
+// evaluateQuery returns the concerts whose row matches every term, and the
+// artists with at least one matching row.
+func evaluateQuery(terms []queryTerm, artists []ArtistWithMeta, events []Event) ([]ArtistWithMeta, []Event) {
+	byArtist := make(map[int][]int, len(artists))
+	for i, ev := range events {
+		byArtist[ev.ArtistID] = append(byArtist[ev.ArtistID], i)
+	}
+	matchAll := func(row queryRow) bool {
+		for _, t := range terms {
+			if !t.Matches(row) {
+				return false
+			}
+		}
+		return true
+	}
+
+	matchedArtists := make([]ArtistWithMeta, 0)
+	matchedEvents := make(map[int]bool)
+	for i := range artists {
+		art := &artists[i]
+		matched := false
+		if len(byArtist[art.ID]) == 0 {
+			matched = matchAll(queryRow{Artist: art})
+		}
+		for _, idx := range byArtist[art.ID] {
+			if matchAll(queryRow{Artist: art, Event: &events[idx]}) {
+				matchedEvents[idx] = true
+				matched = true
+			}
+		}
+		if matched {
+			matchedArtists = append(matchedArtists, *art)
+		}
+	}
+
+	outEvents := make([]Event, 0, len(matchedEvents))
+	for i, ev := range events {
+		if matchedEvents[i] {
+			outEvents = append(outEvents, ev)
+		}
+	}
+	return matchedArtists, outEvents
+}
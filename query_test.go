@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	terms, err := parseQuery(`member:freddie country:uk year>=1980 -city:london "pink floyd"`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(terms) != 5 {
+		t.Fatalf("expected 5 terms, got %+v", terms)
+	}
+	if terms[2].Field != "year" || terms[2].Op != ">=" || terms[2].number != 1980 {
+		t.Fatalf("unexpected comparison term %+v", terms[2])
+	}
+	if !terms[3].Negate || terms[3].Field != "city" || terms[3].Value != "london" {
+		t.Fatalf("unexpected negated term %+v", terms[3])
+	}
+	if terms[4].Field != "" || terms[4].Value != "pink floyd" {
+		t.Fatalf("unexpected phrase term %+v", terms[4])
+	}
+
+	// Punctuation in a bare word that is not a field stays part of the text.
+	for src, want := range map[string]string{`Wham!`: "Wham!", `wham! x`: "wham!", `Hello:`: "Hello:", `Oh!!`: "Oh!!"} {
+		terms, err := parseQuery(src)
+		if err != nil || len(terms) == 0 || terms[0].Field != "" || terms[0].Value != want {
+			t.Errorf("%q: expected free text %q, got %+v (%v)", src, want, terms, err)
+		}
+	}
+
+	errors := map[string]struct {
+		pos  int
+		code string
+	}{
+		`Wham!=x`:         {0, codeQueryUnknownField},
+		`genre:rock`:      {0, codeQueryUnknownField},
+		`year>=abc`:       {6, codeQueryExpectedNumber},
+		`name>queen`:      {4, codeQueryTextOperator},
+		`member:"freddie`: {7, codeQueryUnclosedQuote},
+		`country:uk  - x`: {12, codeQueryDanglingNegation},
+		`city:`:           {5, codeQueryMissingValue},
+		`:x`:              {0, codeQueryMissingField},
+		`date:2020`:       {5, codeQueryExpectedDate},
+	}
+	for src, want := range errors {
+		_, err := parseQuery(src)
+		qe, ok := err.(*queryError)
+		if !ok {
+			t.Fatalf("%q: expected queryError, got %v", src, err)
+		}
+		if qe.Pos != want.pos || qe.Code != want.code {
+			t.Errorf("%q: %s at %d, want %s at %d (%s)", src, qe.Code, qe.Pos, want.code, want.pos, qe)
+		}
+	}
+}
+
+func TestEvaluateQuery(t *testing.T) {
+	bundle := DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury"}, CreationDate: 1970},
+			{ID: 2, Name: "Other", Members: []string{"Freddie Other"}, CreationDate: 1990},
+		},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{
+				"london-uk":     {"13-07-1985"},
+				"manchester-uk": {"01-06-1986"},
+				"paris-france":  {"01-06-1986"},
+			}},
+			{ID: 2, DatesLocations: map[string][]string{"london-uk": {"01-01-1995"}}},
+		},
+	}
+	terms, err := parseQuery(`member:freddie country:uk year>=1980 -city:london`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	artists, events := evaluateQuery(terms, mergeArtists(bundle), buildEvents(bundle.Artists, bundle.Relations))
+	if len(artists) != 1 || artists[0].Name != "Queen" {
+		t.Fatalf("unexpected artists %+v", artists)
+	}
+	if len(events) != 1 || events[0].City != "Manchester" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
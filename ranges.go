@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paramError describes a rejected query parameter. Code names an entry of
+// problemCatalog and Args fill its detail template.
+type paramError struct {
+	Param string
+	Code  string
+	Args  []interface{}
+}
+
+func newParamError(param, code string, args ...interface{}) *paramError {
+	return &paramError{Param: param, Code: code, Args: args}
+}
+
+// Error returns the message in the default language.
+func (e *paramError) Error() string {
+	return problemMessage(e.Code, e.Args...)
+}
+
+// intRange is an inclusive bound pair; unset sides are open.
+type intRange struct {
+	Min, Max       int
+	HasMin, HasMax bool
+}
+
+// Active reports whether at least one bound is set.
+func (r intRange) Active() bool {
+	return r.HasMin || r.HasMax
+}
+
+// Contains reports whether v lies within the range.
+func (r intRange) Contains(v int) bool {
+	if r.HasMin && v < r.Min {
+		return false
+	}
+	if r.HasMax && v > r.Max {
+		return false
+	}
+	return true
+}
+
+// parseIntRange reads two non-negative integer parameters as an inclusive range.
+func parseIntRange(q url.Values, minKey, maxKey string) (intRange, error) {
+	var r intRange
+	var err error
+	if r.Min, r.HasMin, err = parseNonNegative(q, minKey); err != nil {
+		return r, err
+	}
+	if r.Max, r.HasMax, err = parseNonNegative(q, maxKey); err != nil {
+		return r, err
+	}
+	return r, r.check(minKey, maxKey)
+}
+
+// parseYearRange is parseIntRange for year parameters where 0 means unset, as
+// album_from and album_to did before they became ranges: 0 must not turn the
+// filter on and drop artists without a parsed year.
+func parseYearRange(q url.Values, minKey, maxKey string) (intRange, error) {
+	var r intRange
+	var err error
+	if r.Min, r.HasMin, err = parseNonNegative(q, minKey); err != nil {
+		return r, err
+	}
+	if r.Max, r.HasMax, err = parseNonNegative(q, maxKey); err != nil {
+		return r, err
+	}
+	r.HasMin = r.HasMin && r.Min > 0
+	r.HasMax = r.HasMax && r.Max > 0
+	return r, r.check(minKey, maxKey)
+}
+
+// check rejects a range whose lower bound exceeds its upper bound.
+func (r intRange) check(minKey, maxKey string) error {
+	if r.HasMin && r.HasMax && r.Min > r.Max {
+		return newParamError(minKey, codeInvalidRange, minKey, r.Min, maxKey, r.Max)
+	}
+	return nil
+}
+
+func parseNonNegative(q url.Values, key string) (int, bool, error) {
+	raw := strings.TrimSpace(q.Get(key))
+	if raw == "" {
+		return 0, false, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return 0, false, newParamError(key, codeInvalidInteger, key, raw)
+	}
+	return v, true, nil
+}
+
+// dateRange is an inclusive range of calendar days; zero-valued sides are open.
+type dateRange struct {
+	From, To time.Time
+}
+
+// Active reports whether at least one bound is set.
+func (r dateRange) Active() bool {
+	return !r.From.IsZero() || !r.To.IsZero()
+}
+
+// Contains reports whether the day of t lies within the range.
+func (r dateRange) Contains(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if !r.From.IsZero() && day.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && day.After(r.To) {
+		return false
+	}
+	return true
+}
+
+// parseDateRange reads two ISO (YYYY-MM-DD) date parameters as an inclusive range.
+func parseDateRange(q url.Values, fromKey, toKey string) (dateRange, error) {
+	var r dateRange
+	var err error
+	if r.From, err = parseISODate(q, fromKey); err != nil {
+		return r, err
+	}
+	if r.To, err = parseISODate(q, toKey); err != nil {
+		return r, err
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return r, newParamError(fromKey, codeInvalidDateRange, fromKey, r.From.Format("2006-01-02"), toKey, r.To.Format("2006-01-02"))
+	}
+	return r, nil
+}
+
+func parseISODate(q url.Values, key string) (time.Time, error) {
+	raw := strings.TrimSpace(q.Get(key))
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	ts, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, newParamError(key, codeInvalidDate, key, raw)
+	}
+	return ts, nil
+}
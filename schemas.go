@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	schemasPath = "/api/schemas"
+	// jsonSchemaDialect is the JSON Schema version the documents follow.
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// publishedSchemas are the response types served as standalone JSON Schema
+// documents, for clients that do not read OpenAPI. Each is named after its
+// OpenAPI component.
+var publishedSchemas = []struct {
+	Type        interface{}
+	Description string
+}{
+	{ArtistWithMeta{}, "An artist of /api/artists and /api/artists/{slug}."},
+	{UnifiedArtist{}, "An artist of /api/artists with source or external set, from Groupie Tracker or Spotify."},
+	{Event{}, "A concert of /api/events."},
+	{viewLocation{}, "A location of /api/locations."},
+	{Relation{}, "An entry of /api/relation."},
+	{spotifyArtistDetail{}, "The artist of /api/spotify/artist."},
+}
+
+// schemaLink is one entry of the /api/schemas index.
+type schemaLink struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+var (
+	schemaDocsOnce sync.Once
+	schemaDocs     map[string]map[string]interface{}
+)
+
+// jsonSchemaDocs builds one document per published type. Each holds the
+// schemas it references under $defs, so it can be used on its own.
+func jsonSchemaDocs() map[string]map[string]interface{} {
+	schemaDocsOnce.Do(func() {
+		schemaDocs = make(map[string]map[string]interface{})
+		for _, s := range publishedSchemas {
+			t := reflect.TypeOf(s.Type)
+			b := &schemaBuilder{components: make(map[string]interface{}), draft: true}
+			root := b.schema(t)
+			name := schemaName(t)
+			schemaDocs[name] = map[string]interface{}{
+				"$schema":     jsonSchemaDialect,
+				"title":       name,
+				"description": s.Description,
+				"$ref":        root["$ref"],
+				"$defs":       b.components,
+			}
+		}
+	})
+	return schemaDocs
+}
+
+// handleSchemas lists the published JSON Schemas.
+func (a *App) handleSchemas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	base := requestBaseURL(r) + schemasPath + "/"
+	links := make([]schemaLink, 0, len(publishedSchemas))
+	for _, s := range publishedSchemas {
+		name := schemaName(reflect.TypeOf(s.Type))
+		links = append(links, schemaLink{Name: name, Description: s.Description, URL: base + name + ".json"})
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+// handleSchemaByName serves /api/schemas/{name}.json; the extension is
+// optional and /api/schemas/ is the index.
+func (a *App) handleSchemaByName(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, schemasPath+"/"), ".json")
+	if name == "" {
+		a.handleSchemas(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	doc, ok := jsonSchemaDocs()[name]
+	if !ok {
+		writeProblem(w, r, codeNotFound)
+		return
+	}
+	// $id depends on the host the document was fetched from.
+	out := make(map[string]interface{}, len(doc)+1)
+	for k, v := range doc {
+		out[k] = v
+	}
+	out["$id"] = requestBaseURL(r) + schemasPath + "/" + name + ".json"
+	writeJSON(w, http.StatusOK, out)
+}
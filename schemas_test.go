@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validate checks v against schema, resolving $ref in root's $defs. It covers
+// the keywords the generated schemas use.
+func validate(root, schema map[string]interface{}, v interface{}, at string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, ok := root["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if !ok {
+			return []string{at + ": unresolved " + ref}
+		}
+		return validate(root, def, v, at)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, alt := range anyOf {
+			if len(validate(root, alt.(map[string]interface{}), v, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{at + ": matches no anyOf branch"}
+	}
+	if typ, ok := schema["type"]; ok {
+		types := []interface{}{typ}
+		if list, ok := typ.([]interface{}); ok {
+			types = list
+		}
+		matched := false
+		for _, t := range types {
+			matched = matched || hasJSONType(v, t.(string))
+		}
+		if !matched {
+			return []string{fmt.Sprintf("%s: %v is not %v", at, v, typ)}
+		}
+	}
+	var errs []string
+	switch val := v.(type) {
+	case string:
+		layout := map[string]string{"date": "2006-01-02", "date-time": time.RFC3339}[fmt.Sprint(schema["format"])]
+		if _, err := time.Parse(layout, val); layout != "" && err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %q is not a %v", at, val, schema["format"]))
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				errs = append(errs, validate(root, items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := val[name.(string)]; !ok {
+					errs = append(errs, fmt.Sprintf("%s: missing %s", at, name))
+				}
+			}
+		}
+		for name, field := range val {
+			if prop, ok := props[name].(map[string]interface{}); ok {
+				errs = append(errs, validate(root, prop, field, at+"."+name)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, fmt.Sprintf("%s: unexpected property %s", at, name))
+				}
+			case map[string]interface{}:
+				errs = append(errs, validate(root, extra, field, at+"."+name)...)
+			}
+		}
+	}
+	return errs
+}
+
+func hasJSONType(v interface{}, typ string) bool {
+	switch val := v.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case float64:
+		return typ == "number" || (typ == "integer" && val == math.Trunc(val))
+	case string:
+		return typ == "string"
+	case []interface{}:
+		return typ == "array"
+	case map[string]interface{}:
+		return typ == "object"
+	}
+	return false
+}
+
+func fetchSchema(t *testing.T, app *App, name string) map[string]interface{} {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, schemasPath+"/"+name+".json", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("schema %s: expected 200, got %d", name, rr.Code)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode schema %s: %v", name, err)
+	}
+	return doc
+}
+
+// checkAgainstSchema validates each element of a JSON array body, or the body
+// itself when it is an object.
+func checkAgainstSchema(t *testing.T, app *App, target, name string) {
+	t.Helper()
+	schema := fetchSchema(t, app, name)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d: %s", target, rr.Code, rr.Body.String())
+	}
+	var body interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: decode: %v", target, err)
+	}
+	values, ok := body.([]interface{})
+	if !ok {
+		values = []interface{}{body}
+	}
+	if len(values) == 0 {
+		t.Fatalf("%s: empty response", target)
+	}
+	for i, v := range values {
+		for _, err := range validate(schema, schema, v, fmt.Sprintf("%s[%d]", target, i)) {
+			t.Errorf("%s does not match %s: %s", target, name, err)
+		}
+	}
+}
+
+func TestHandlersMatchPublishedSchemas(t *testing.T) {
+	app := graphQLTestApp()
+	for target, name := range map[string]string{
+		"/api/artists":                "ArtistWithMeta",
+		"/api/artists/queen":          "ArtistWithMeta",
+		"/api/artists?source=groupie": "UnifiedArtist",
+		"/api/events":                 "Event",
+		"/api/locations":              "ViewLocation",
+		"/api/relation":               "Relation",
+	} {
+		checkAgainstSchema(t, app, target, name)
+	}
+}
+
+// roundTripFunc fakes the Spotify API.
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r), nil }
+
+func TestSpotifyArtistMatchesPublishedSchema(t *testing.T) {
+	app := graphQLTestApp()
+	app.spotify = newSpotifyClient("id", "secret")
+	app.spotify.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) *http.Response {
+		body := `{"access_token":"token","token_type":"Bearer","expires_in":3600}`
+		if r.URL.Host == "api.spotify.com" {
+			// Spotify omits genres for some artists; they encode as null.
+			body = `{"id":"sp1","name":"Queen","popularity":80,"followers":{"total":100},"images":[{"url":"queen.jpg","height":640,"width":640}]}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body)), Request: r}
+	})}
+	checkAgainstSchema(t, app, "/api/spotify/artist?id=sp1", "SpotifyArtistDetail")
+}
+
+func TestSchemaRejectsWrongTypes(t *testing.T) {
+	app := graphQLTestApp()
+	schema := fetchSchema(t, app, "ArtistWithMeta")
+	artist := map[string]interface{}{
+		"id": 1, "image": "", "name": "Queen", "members": nil, "creationDate": "1970",
+		"firstAlbum": "13-07-1973", "concertDates": "", "relations": "", "slug": "queen",
+		"locations": []interface{}{}, "firstAlbumDate": "13-07-1973", "extra": true,
+	}
+	var decoded interface{}
+	raw, _ := json.Marshal(artist)
+	_ = json.Unmarshal(raw, &decoded)
+	errs := validate(schema, schema, decoded, "artist")
+	sort.Strings(errs)
+	want := []string{
+		`artist.creationDate: 1970 is not integer`,
+		`artist.firstAlbumDate: "13-07-1973" is not a date`,
+		`artist: unexpected property extra`,
+	}
+	if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected errors:\n%s", strings.Join(errs, "\n"))
+	}
+}
+
+func TestSchemasIndexAndNotFound(t *testing.T) {
+	app := newTestApp()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, schemasPath, nil)
+	req.Host = "example.org"
+	app.routes().ServeHTTP(rr, req)
+	var links []schemaLink
+	if err := json.Unmarshal(rr.Body.Bytes(), &links); err != nil || len(links) != len(publishedSchemas) {
+		t.Fatalf("unexpected index %d %s", rr.Code, rr.Body.String())
+	}
+	if links[0].URL != "http://example.org/api/schemas/ArtistWithMeta.json" {
+		t.Fatalf("unexpected url %s", links[0].URL)
+	}
+	doc := fetchSchema(t, app, "Event")
+	if doc["$schema"] != jsonSchemaDialect || doc["$id"] != "http://example.com/api/schemas/Event.json" {
+		t.Fatalf("unexpected header %v %v", doc["$schema"], doc["$id"])
+	}
+
+	rr, p := serveProblem(t, app, http.MethodGet, schemasPath+"/Nope.json", nil)
+	if rr.Code != http.StatusNotFound || p.Code != codeNotFound {
+		t.Fatalf("unexpected %d %+v", rr.Code, p)
+	}
+}
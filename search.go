@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Search hit types returned by /api/search.
+const (
+	hitArtist   = "artist"
+	hitMember   = "member"
+	hitLocation = "location"
+	hitEvent    = "event"
+)
+
+// Field weights used when indexing. Names count more than incidental matches such
+// as a member of a band or the year it was formed.
+const (
+	weightName   = 3.0
+	weightMember = 1.5
+	weightPlace  = 2.0
+	weightYear   = 1.0
+	prefixFactor = 0.5
+	exactBonus   = 2.0
+)
+
+// SearchHit is a single ranked result from the search index.
+type SearchHit struct {
+	Type     string  `json:"type"`
+	ID       string  `json:"id"`
+	Label    string  `json:"label"`
+	Detail   string  `json:"detail,omitempty"`
+	ArtistID int     `json:"artistId,omitempty"`
+	Date     string  `json:"date,omitempty"`
+	Score    float64 `json:"score"`
+}
+
+type posting struct {
+	doc    int
+	weight float64
+}
+
+// searchIndex is an immutable inverted index over artists, members, locations and
+// events. It is rebuilt from scratch each time the cache is refreshed.
+type searchIndex struct {
+	docs     []SearchHit
+	labels   []string
+	postings map[string][]posting
+	terms    []string
+}
+
+// tokenize normalises text and splits it into words.
+func tokenize(text string) []string {
+	return strings.Fields(normalizeText(text))
+}
+
+func (idx *searchIndex) addDoc(hit SearchHit) int {
+	idx.docs = append(idx.docs, hit)
+	idx.labels = append(idx.labels, strings.Join(tokenize(hit.Label), " "))
+	return len(idx.docs) - 1
+}
+
+// addText indexes every token of text for doc, keeping the best weight per term.
+func (idx *searchIndex) addText(doc int, text string, weight float64) {
+	for _, tok := range tokenize(text) {
+		list := idx.postings[tok]
+		if n := len(list); n > 0 && list[n-1].doc == doc {
+			if weight > list[n-1].weight {
+				list[n-1].weight = weight
+			}
+			continue
+		}
+		idx.postings[tok] = append(list, posting{doc: doc, weight: weight})
+	}
+}
+
+// buildSearchIndex indexes the merged views of a data bundle.
+func buildSearchIndex(bundle DataBundle) *searchIndex {
+	idx := &searchIndex{postings: make(map[string][]posting)}
+	artists := mergeArtists(bundle)
+
+	for _, art := range artists {
+		doc := idx.addDoc(SearchHit{
+			Type:     hitArtist,
+			ID:       art.Slug,
+			Label:    art.Name,
+			Detail:   strings.Join(art.Members, ", "),
+			ArtistID: art.ID,
+		})
+		idx.addText(doc, art.Name, weightName)
+		for _, m := range art.Members {
+			idx.addText(doc, m, weightMember)
+		}
+		if art.CreationDate > 0 {
+			idx.addText(doc, strconv.Itoa(art.CreationDate), weightYear)
+		}
+		if art.FirstAlbumYear > 0 {
+			idx.addText(doc, strconv.Itoa(art.FirstAlbumYear), weightYear)
+		}
+	}
+
+	for _, m := range buildMembers(bundle.Artists) {
+		bands := make([]string, 0, len(m.Bands))
+		for _, b := range m.Bands {
+			bands = append(bands, b.ArtistName)
+		}
+		doc := idx.addDoc(SearchHit{Type: hitMember, ID: m.ID, Label: m.Name, Detail: strings.Join(bands, ", ")})
+		idx.addText(doc, m.Name, weightName)
+	}
+
+	events := buildEvents(bundle.Artists, bundle.Relations)
+	for _, city := range summarizeCities(events) {
+		doc := idx.addDoc(SearchHit{
+			Type:   hitLocation,
+			ID:     city.Slug,
+			Label:  strings.TrimSpace(city.Name + ", " + city.Country),
+			Detail: fmt.Sprintf("%d concerts", city.ConcertCount),
+		})
+		idx.addText(doc, city.Name, weightName)
+		idx.addText(doc, city.Country, weightPlace)
+	}
+
+	for _, ev := range events {
+		doc := idx.addDoc(SearchHit{
+			Type:     hitEvent,
+			ID:       fmt.Sprintf("%d-%s-%s", ev.ArtistID, ev.Location, ev.DateISO),
+			Label:    ev.ArtistName,
+			Detail:   strings.TrimSpace(ev.City + ", " + ev.Country),
+			ArtistID: ev.ArtistID,
+			Date:     ev.DateISO,
+		})
+		idx.addText(doc, ev.ArtistName, weightPlace)
+		idx.addText(doc, ev.City, weightPlace)
+		idx.addText(doc, ev.Country, weightPlace)
+		idx.addText(doc, strconv.Itoa(ev.Date.Year()), weightYear)
+	}
+
+	idx.terms = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+	return idx
+}
+
+// matchToken scores every document containing tok, either exactly or as a prefix
+// of a longer term. Prefix matches are discounted so complete words rank first.
+func (idx *searchIndex) matchToken(tok string) map[int]float64 {
+	scores := make(map[int]float64)
+	start := sort.SearchStrings(idx.terms, tok)
+	for i := start; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], tok); i++ {
+		factor := prefixFactor
+		if idx.terms[i] == tok {
+			factor = 1
+		}
+		for _, p := range idx.postings[idx.terms[i]] {
+			if w := p.weight * factor; w > scores[p.doc] {
+				scores[p.doc] = w
+			}
+		}
+	}
+	return scores
+}
+
+var hitTypeOrder = map[string]int{hitArtist: 0, hitMember: 1, hitLocation: 2, hitEvent: 3}
+
+// Search returns documents matching every token of query, best first.
+// When types is non-empty only those hit types are returned.
+func (idx *searchIndex) Search(query string, types map[string]bool) []SearchHit {
+	tokens := tokenize(query)
+	if idx == nil || len(tokens) == 0 {
+		return []SearchHit{}
+	}
+	var total map[int]float64
+	for _, tok := range tokens {
+		scores := idx.matchToken(tok)
+		if total == nil {
+			total = scores
+			continue
+		}
+		for doc := range total {
+			if s, ok := scores[doc]; ok {
+				total[doc] += s
+			} else {
+				delete(total, doc)
+			}
+		}
+	}
+
+	phrase := strings.Join(tokens, " ")
+	hits := make([]SearchHit, 0, len(total))
+	for doc, score := range total {
+		hit := idx.docs[doc]
+		if len(types) > 0 && !types[hit.Type] {
+			continue
+		}
+		if idx.labels[doc] == phrase {
+			score += exactBonus
+		}
+		hit.Score = score
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hitTypeOrder[hits[i].Type] != hitTypeOrder[hits[j].Type] {
+			return hitTypeOrder[hits[i].Type] < hitTypeOrder[hits[j].Type]
+		}
+		if hits[i].Label != hits[j].Label {
+			return hits[i].Label < hits[j].Label
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
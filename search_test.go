@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func testSearchBundle() DataBundle {
+	return DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury", "Brian May"}, CreationDate: 1970},
+			{ID: 2, Name: "Queens of the Stone Age", Members: []string{"Josh Homme"}, CreationDate: 1996},
+			{ID: 3, Name: "Mercury Rev", Members: []string{"Jonathan Donahue"}, CreationDate: 1989},
+		},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{"london-uk": {"13-07-1985"}}},
+			{ID: 3, DatesLocations: map[string][]string{"mercury-usa": {"01-01-2001"}}},
+		},
+	}
+}
+
+func TestSearchIndexRanking(t *testing.T) {
+	idx := buildSearchIndex(testSearchBundle())
+
+	hits := idx.Search("queen", map[string]bool{hitArtist: true})
+	if len(hits) != 2 || hits[0].ID != "queen" {
+		t.Fatalf("expected exact artist match first, got %+v", hits)
+	}
+	if hits[0].Score <= hits[1].Score {
+		t.Fatalf("expected exact match to outrank prefix match: %+v", hits)
+	}
+
+	hits = idx.Search("mercury", nil)
+	if len(hits) == 0 || hits[0].Type != hitArtist || hits[0].ID != "mercury-rev" {
+		t.Fatalf("expected band name to rank before member, got %+v", hits)
+	}
+	types := make(map[string]bool)
+	for _, h := range hits {
+		types[h.Type] = true
+	}
+	for _, want := range []string{hitArtist, hitMember, hitLocation, hitEvent} {
+		if !types[want] {
+			t.Fatalf("missing %s hit in %+v", want, hits)
+		}
+	}
+
+	hits = idx.Search("london 1985", nil)
+	if len(hits) != 1 || hits[0].Type != hitEvent || hits[0].ArtistID != 1 {
+		t.Fatalf("expected a single event hit, got %+v", hits)
+	}
+}
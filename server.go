@@ -8,8 +8,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"syscall"
 	"time"
 )
 
@@ -17,6 +18,10 @@ const (
 	defaultAddr      = ":8080"
 	defaultStaticDir = "static"
 	defaultTplGlob   = "templates/*.html"
+	// shutdownTimeout is how long in-flight requests get to finish on SIGINT or SIGTERM.
+	shutdownTimeout = 10 * time.Second
+	// defaultRefreshInterval is how often upstream data is reloaded.
+	defaultRefreshInterval = 30 * time.Minute
 )
 
 // App bundles the HTTP handlers, template set and data cache.
@@ -26,6 +31,13 @@ type App struct {
 	spotify   *SpotifyClient
 	templates *template.Template
 	staticDir string
+	// now overrides the clock used for time windows; nil means time.Now.
+	now func() time.Time
+	// analytics aggregates searches; nil disables recording.
+	analytics  *searchAnalytics
+	adminToken string
+	// stream publishes data changes to /api/stream; nil disables it.
+	stream *eventHub
 }
 
 func newApp(apiBase, staticDir, tplGlob, spotifyID, spotifySecret string) (*App, error) {
@@ -44,18 +56,101 @@ func newApp(apiBase, staticDir, tplGlob, spotifyID, spotifySecret string) (*App,
 		spotify:   spotifyClient,
 		templates: tpls,
 		staticDir: staticDir,
+		analytics: newSearchAnalytics(),
+		stream:    newEventHub(),
 	}, nil
 }
 
+func (a *App) currentTime() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
 func (a *App) refreshData(ctx context.Context) error {
 	data, err := a.api.FetchAll(ctx)
 	if err != nil {
 		return err
 	}
-	a.cache.Set(data)
+	a.setData(data)
 	return nil
 }
 
+// setData replaces the cached data and tells stream clients what changed.
+func (a *App) setData(data DataBundle) {
+	a.stream.publishChange(a.cache.Set(data))
+}
+
+// refreshEvery reloads the upstream data on a fixed interval, so new concerts
+// reach the feeds without a restart.
+func (a *App) refreshEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		if err := a.refreshData(ctx); err != nil {
+			log.Printf("refresh data: %v", err)
+		}
+		cancel()
+	}
+}
+
+// apiRoute is a JSON API route. Patterns ending in "/" serve sub-paths.
+type apiRoute struct {
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// apiRoutes lists the JSON API. Every route must be described in openapi.go.
+func (a *App) apiRoutes() []apiRoute {
+	return []apiRoute{
+		{"/api/artists", a.handleAPIArtists},
+		{"/api/artists/", a.handleAPIArtistByID},
+		{"/api/members", a.handleAPIMembers},
+		{"/api/members/", a.handleAPIMemberByID},
+		{"/api/locations", a.handleAPILocations},
+		{"/api/dates", a.handleAPIDates},
+		{"/api/relation", a.handleAPIRelation},
+		{"/api/events", a.handleAPIEvents},
+		{"/api/events.ics", a.handleAPIEventsICS},
+		{"/api/search", a.handleAPISearch},
+		{"/api/suggest", a.handleAPISuggest},
+		{"/api/query", a.handleAPIQuery},
+		{"/api/countries", a.handleAPICountries},
+		{"/api/countries/", a.handleAPICountryByCode},
+		{"/api/cities", a.handleAPICities},
+		{"/api/cities/", a.handleAPICityBySlug},
+		{"/api/spotify/artist", a.handleAPISpotifyArtist},
+		{"/api/admin/search-stats", a.handleAdminSearchStats},
+		{"/api/openapi.json", a.handleAPIOpenAPI},
+		{schemasPath, a.handleSchemas},
+		{schemasPath + "/", a.handleSchemaByName},
+		{batchPath, a.handleBatch},
+		{streamPath, a.handleStream},
+		{apiV2Prefix + "/artists", a.handleV2Artists},
+		{apiV2Prefix + "/artists/", a.handleV2ArtistByID},
+		{apiV2Prefix + "/members", a.handleV2Members},
+		{apiV2Prefix + "/members/", a.handleV2MemberByID},
+		{apiV2Prefix + "/locations", a.handleV2Locations},
+		{apiV2Prefix + "/locations/", a.handleV2LocationByID},
+		{apiV2Prefix + "/events", a.handleV2Events},
+		{apiV2Prefix + "/events/", a.handleV2EventByID},
+		{apiV2Prefix + "/", a.handleV2NotFound},
+	}
+}
+
+// serviceRoutes lists the machine-readable endpoints outside /api. They are
+// documented in openapi.go too, but not reachable from /api/batch.
+func (a *App) serviceRoutes() []apiRoute {
+	return []apiRoute{
+		{"/graphql", a.handleGraphQL},
+		{"/feeds/concerts.atom", a.handleConcertFeed},
+		{"/feeds/concerts.rss", a.handleConcertFeed},
+		{"/healthz", a.handleHealth},
+	}
+}
+
 func (a *App) routes() http.Handler {
 	mux := http.NewServeMux()
 
@@ -68,18 +163,17 @@ func (a *App) routes() http.Handler {
 	mux.HandleFunc("/favicon.ico", a.handleFavicon)
 
 	// API endpoints
-	mux.HandleFunc("/api/artists", a.handleAPIArtists)
-	mux.HandleFunc("/api/artists/", a.handleAPIArtistByID)
-	mux.HandleFunc("/api/locations", a.handleAPILocations)
-	mux.HandleFunc("/api/dates", a.handleAPIDates)
-	mux.HandleFunc("/api/relation", a.handleAPIRelation)
-	mux.HandleFunc("/api/events", a.handleAPIEvents)
-	mux.HandleFunc("/api/spotify/artist", a.handleAPISpotifyArtist)
-	mux.HandleFunc("/healthz", a.handleHealth)
+	for _, route := range a.apiRoutes() {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
+	for _, route := range a.serviceRoutes() {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
 
 	// HTML pages
 	mux.HandleFunc("/artist", a.handleArtistPage)
 	mux.HandleFunc("/artist.html", a.handleArtistPage)
+	mux.HandleFunc("/artist/", a.handleArtistPage)
 	mux.HandleFunc("/artist-spotify", a.handleSpotifyArtistPage)
 	mux.HandleFunc("/artist-spotify.html", a.handleSpotifyArtistPage)
 	mux.HandleFunc("/dates", a.handleDatesPage)
@@ -116,6 +210,14 @@ func main() {
 	if v := os.Getenv("TEMPLATES"); v != "" {
 		tplDefault = v
 	}
+	refreshDefault := defaultRefreshInterval
+	if v := os.Getenv("REFRESH_INTERVAL"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid REFRESH_INTERVAL %q: %v", v, err)
+		}
+		refreshDefault = parsed
+	}
 
 	addr := flag.String("addr", addrDefault, "HTTP address to listen on (e.g. :8080)")
 	apiBase := flag.String("api", apiDefault, "Upstream Groupie Tracker API base URL")
@@ -123,18 +225,31 @@ func main() {
 	tplGlob := flag.String("templates", tplDefault, "Glob pattern for HTML templates")
 	spotifyID := flag.String("spotify-client-id", os.Getenv("SPOTIFY_CLIENT_ID"), "Spotify Client ID (defaults to SPOTIFY_CLIENT_ID env)")
 	spotifySecret := flag.String("spotify-client-secret", os.Getenv("SPOTIFY_CLIENT_SECRET"), "Spotify Client Secret (defaults to SPOTIFY_CLIENT_SECRET env)")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "Bearer token for /api/admin endpoints (defaults to ADMIN_TOKEN env; empty disables them)")
+	refreshInterval := flag.Duration("refresh-interval", refreshDefault, "How often upstream data is reloaded (defaults to REFRESH_INTERVAL env; 0 disables)")
+	analyticsFile := flag.String("analytics-file", os.Getenv("ANALYTICS_FILE"), "File where search analytics are persisted (defaults to ANALYTICS_FILE env; empty keeps them in memory)")
 	flag.Parse()
 
 	app, err := newApp(*apiBase, *staticDir, *tplGlob, *spotifyID, *spotifySecret)
 	if err != nil {
 		log.Fatalf("initialise app: %v", err)
 	}
+	app.adminToken = *adminToken
+	if *analyticsFile != "" {
+		if err := app.analytics.Load(*analyticsFile); err != nil {
+			log.Printf("warning: failed to load search analytics: %v", err)
+		}
+		go app.analytics.persistEvery(*analyticsFile, time.Minute)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 	if err := app.refreshData(ctx); err != nil {
 		log.Printf("warning: failed to prefetch data: %v", err)
 	}
+	if *refreshInterval > 0 {
+		go app.refreshEvery(*refreshInterval)
+	}
 
 	srv := &http.Server{
 		Addr:              *addr,
@@ -145,9 +260,32 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
-	log.Printf("Groupie Tracker backend running at http://localhost%s", *addr)
-	if err := srv.ListenAndServe(); err != nil && !strings.Contains(err.Error(), "Server closed") {
+	stop, cancelStop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelStop()
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Groupie Tracker backend running at http://localhost%s", *addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
 		log.Fatalf("server error: %v", err)
+	case <-stop.Done():
+	}
+	log.Printf("shutting down")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Long-lived responses such as the event stream keep Shutdown waiting.
+		log.Printf("graceful shutdown: %v", err)
+		srv.Close()
+	}
+	// Statistics since the last periodic save would otherwise be lost.
+	if *analyticsFile != "" {
+		if err := app.analytics.Save(*analyticsFile); err != nil {
+			log.Printf("save search analytics: %v", err)
+		}
 	}
 }
 
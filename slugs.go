@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// slugSymbols spells out symbols that carry meaning in band names.
+var slugSymbols = strings.NewReplacer("&", " and ", "+", " plus ", "$", "s")
+
+// slugify builds a lowercase ASCII slug from a display name, reusing the shared
+// text folding so accented names transliterate ("Motörhead" -> "motorhead").
+func slugify(name string) string {
+	words := strings.FieldsFunc(normalizeText(slugSymbols.Replace(name)), func(r rune) bool {
+		return r == ' ' || r > unicode.MaxASCII
+	})
+	return strings.Join(words, "-")
+}
+
+// assignSlugs sets a unique slug on every artist. Artists are processed by ID so
+// the lowest ID keeps the bare slug and later collisions get their ID appended
+// (and a counter if that is taken too), which keeps existing links stable when new artists are added upstream.
+func assignSlugs(artists []ArtistWithMeta) {
+	idx := make([]int, len(artists))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return artists[idx[i]].ID < artists[idx[j]].ID })
+
+	used := make(map[string]bool, len(artists))
+	for _, i := range idx {
+		id := strconv.Itoa(artists[i].ID)
+		base := slugify(artists[i].Name)
+		if base == "" || isNumeric(base) {
+			// Numeric slugs would be mistaken for upstream IDs.
+			base = "artist-" + strings.TrimPrefix(base+"-"+id, "-")
+		}
+		slug := base
+		// Another artist's name may already own base-id ("Foo 12" next to a
+		// second "Foo" with ID 12), so keep counting until the slug is free.
+		for n := 1; used[slug]; n++ {
+			slug = base + "-" + id
+			if n > 1 {
+				slug += "-" + strconv.Itoa(n)
+			}
+		}
+		used[slug] = true
+		artists[i].Slug = slug
+	}
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// findArtist resolves either a numeric upstream ID or a slug.
+func findArtist(artists []ArtistWithMeta, key string) (ArtistWithMeta, bool) {
+	if isNumeric(key) {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return ArtistWithMeta{}, false
+		}
+		for _, art := range artists {
+			if art.ID == id {
+				return art, true
+			}
+		}
+		return ArtistWithMeta{}, false
+	}
+	key = strings.ToLower(key)
+	for _, art := range artists {
+		if art.Slug == key {
+			return art, true
+		}
+	}
+	return ArtistWithMeta{}, false
+}
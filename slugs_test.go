@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Queen":            "queen",
+		"Guns N' Roses":    "guns-n-roses",
+		"AC/DC":            "ac-dc",
+		"Motörhead":        "motorhead",
+		"Mumford & Sons":   "mumford-and-sons",
+		"  Sigur Rós  ":    "sigur-ros",
+		"Die Ärzte":        "die-arzte",
+		"R.E.M.":           "rem",
+		"Straßenjungs":     "strassenjungs",
+		"Thirty Seconds …": "thirty-seconds",
+	}
+	for in, want := range tests {
+		if got := slugify(in); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAssignSlugsCollisions(t *testing.T) {
+	artists := []ArtistWithMeta{
+		{Artist: Artist{ID: 9, Name: "Queen"}},
+		{Artist: Artist{ID: 3, Name: "queen"}},
+		{Artist: Artist{ID: 4, Name: "311"}},
+		{Artist: Artist{ID: 5, Name: "???"}},
+	}
+	assignSlugs(artists)
+	want := []string{"queen-9", "queen", "artist-311-4", "artist-5"}
+	for i, art := range artists {
+		if art.Slug != want[i] {
+			t.Errorf("artist %d slug = %q, want %q", art.ID, art.Slug, want[i])
+		}
+	}
+}
+
+func TestAssignSlugsFallbackCollision(t *testing.T) {
+	artists := []ArtistWithMeta{
+		{Artist: Artist{ID: 1, Name: "Foo"}},
+		{Artist: Artist{ID: 5, Name: "Foo 12"}},
+		{Artist: Artist{ID: 7, Name: "Foo 12 2"}},
+		{Artist: Artist{ID: 12, Name: "Foo"}},
+	}
+	assignSlugs(artists)
+	want := []string{"foo", "foo-12", "foo-12-2", "foo-12-3"}
+	seen := make(map[string]int)
+	for i, art := range artists {
+		if art.Slug != want[i] {
+			t.Errorf("artist %d slug = %q, want %q", art.ID, art.Slug, want[i])
+		}
+		if other, ok := seen[art.Slug]; ok {
+			t.Errorf("artists %d and %d share slug %q", other, art.ID, art.Slug)
+		}
+		seen[art.Slug] = art.ID
+	}
+	if art, ok := findArtist(artists, "foo-12"); !ok || art.ID != 5 {
+		t.Errorf("foo-12 resolved to %+v", art.Artist)
+	}
+}
@@ -1,15 +1,22 @@
 // JavaScript for the Groupie Tracker artist detail page.
 // Fetches artist info and relations, then renders list and timeline views.
 
-async function fetchArtists() {
-  const res = await fetch('/api/artists');
+// The artist is addressed by slug (/artist/queen) or, for old links, by ?id=.
+function artistKey() {
+  const match = window.location.pathname.match(/^\/artist\/([^/]+)\/?$/);
+  if (match) return decodeURIComponent(match[1]);
+  return new URLSearchParams(window.location.search).get('id');
+}
+
+async function fetchArtist(key) {
+  const res = await fetch(`/api/artists/${encodeURIComponent(key)}`);
+  if (res.status === 404) return null;
   if (!res.ok) throw new Error(`HTTP ${res.status}`);
-  const data = await res.json();
-  return Array.isArray(data) ? data : [];
+  return res.json();
 }
 
-async function fetchRelations() {
-  const res = await fetch('/api/relation');
+async function fetchRelations(artistId) {
+  const res = await fetch(`/api/relation?id=${encodeURIComponent(artistId)}`);
   if (!res.ok) throw new Error(`HTTP ${res.status}`);
   const data = await res.json();
   if (Array.isArray(data)) return data;
@@ -181,22 +188,21 @@ function renderConcertTimeline(concerts) {
 }
 
 document.addEventListener('DOMContentLoaded', async () => {
-  const params = new URLSearchParams(window.location.search);
-  const id = params.get('id');
-  if (!id) {
+  const key = artistKey();
+  if (!key) {
     window.location.href = '/404';
     return;
   }
   try {
-    const [artists, relations] = await Promise.all([fetchArtists(), fetchRelations()]);
-    const artist = artists.find((a) => Number(a.id) === Number(id));
+    const artist = await fetchArtist(key);
     if (!artist) {
       window.location.href = '/404';
       return;
     }
+    const relations = await fetchRelations(artist.id);
     renderArtistHeader(artist);
     renderMembers(Array.isArray(artist.members) ? artist.members : []);
-    const concerts = buildConcerts(id, relations);
+    const concerts = buildConcerts(artist.id, relations);
     renderConcertList(concerts);
     renderConcertTimeline(concerts);
 
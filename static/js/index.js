@@ -33,7 +33,8 @@ async function fetchArtists(term) {
   const params = new URLSearchParams();
   if (term) params.set('name', term);
   if (currentSource) params.set('source', currentSource);
-  params.set('limit', '8');
+  params.set('spotify_limit', '8');
+  params.set('fields', 'name,image,image_url,source,creationDate,firstAlbum,firstAlbumYear,members,genres,popularity');
   const url = `/api/artists?${params.toString()}`;
   const res = await fetch(url);
   if (!res.ok) {
@@ -43,6 +44,32 @@ async function fetchArtists(term) {
   return Array.isArray(data) ? data : [];
 }
 
+let suggestTimer = null;
+
+// Fills the search datalist from /api/suggest; called on input with a short debounce.
+async function updateSuggestions(term) {
+  const list = document.getElementById('search-suggestions');
+  if (!list) return;
+  if (!term) {
+    list.innerHTML = '';
+    return;
+  }
+  try {
+    const res = await fetch(`/api/suggest?q=${encodeURIComponent(term)}`);
+    if (!res.ok) return;
+    const suggestions = await res.json();
+    list.innerHTML = '';
+    (Array.isArray(suggestions) ? suggestions : []).forEach((s) => {
+      const option = document.createElement('option');
+      option.value = s.value;
+      option.label = s.detail ? `${s.type} · ${s.detail}` : s.type;
+      list.appendChild(option);
+    });
+  } catch (err) {
+    console.error(err);
+  }
+}
+
 function placeholderAvatar(name) {
   const initial = (name || '?').trim().charAt(0).toUpperCase() || '?';
   const wrapper = document.createElement('div');
@@ -59,7 +86,9 @@ function renderArtists(artists) {
   artists.forEach((artist) => {
     const imageURL = artist.image_url || artist.image || '';
     const isSpotify = (artist.source || '').toLowerCase() === 'spotify';
-    const target = isSpotify ? `/artist-spotify?id=${artist.id}` : `artist?id=${artist.id}`;
+    const target = isSpotify
+      ? `/artist-spotify?id=${artist.id}`
+      : (artist.slug ? `/artist/${artist.slug}` : `/artist?id=${artist.id}`);
     const badgeLabel = isSpotify ? 'Spotify' : 'Groupie Tracker';
     const badgeClass = isSpotify ? 'badge-spotify' : 'badge-groupie';
     const metaText = isSpotify
@@ -78,7 +107,7 @@ function renderArtists(artists) {
       : `${membersCount} membres`;
     const rightDetail = isSpotify
       ? (artist.popularity ? `Popularité ${artist.popularity}` : '')
-      : (albumYear(artist.firstAlbum) || artist.creationDate || '');
+      : (artist.firstAlbumYear || albumYear(artist.firstAlbum) || artist.creationDate || '');
 
     const media = document.createElement('div');
     media.className = 'artist-media';
@@ -204,6 +233,12 @@ document.addEventListener('DOMContentLoaded', async () => {
     showError('Échec du chargement des artistes. Veuillez réessayer plus tard.');
   }
 
+  input.addEventListener('input', () => {
+    clearTimeout(suggestTimer);
+    const term = input.value.trim();
+    suggestTimer = setTimeout(() => updateSuggestions(term), 150);
+  });
+
   form.addEventListener('submit', async (e) => {
     e.preventDefault();
     showError('');
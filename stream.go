@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const streamPath = "/api/stream"
+
+// Event types of /api/stream.
+const (
+	streamRefresh        = "refresh"
+	streamConcertAdded   = "concert.added"
+	streamConcertRemoved = "concert.removed"
+	streamSpotifyLinked  = "spotify.linked"
+	// streamReset tells a resuming client that events were lost and it
+	// should reload its data.
+	streamReset = "reset"
+)
+
+var streamTypes = []string{streamRefresh, streamConcertAdded, streamConcertRemoved, streamSpotifyLinked}
+
+const (
+	// maxStreamEvents bounds the replay buffer behind Last-Event-ID.
+	maxStreamEvents = 512
+	// maxConcertEvents bounds the concert events of one refresh; the refresh
+	// event still carries the full counts.
+	maxConcertEvents = 100
+	// defaultStreamHeartbeat keeps proxies from closing idle streams.
+	defaultStreamHeartbeat = 10 * time.Second
+	// streamWriteWindow is how long one write may take before the client is
+	// considered gone. It replaces the server-wide WriteTimeout, which would
+	// otherwise end every stream after 15 seconds.
+	streamWriteWindow = 30 * time.Second
+	streamRetry       = 5 * time.Second
+)
+
+// streamEvent is one published event. Artist and Country scope it for topic
+// filters; global events leave them empty.
+type streamEvent struct {
+	ID       uint64
+	Type     string
+	ArtistID int
+	Country  string
+	Data     json.RawMessage
+}
+
+type refreshPayload struct {
+	Version   string    `json:"version"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Added     int       `json:"added"`
+	Removed   int       `json:"removed"`
+	// Truncated is set when only the first concerts were sent as events.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type spotifyLinkPayload struct {
+	ArtistID   int    `json:"artistId"`
+	ArtistSlug string `json:"artistSlug"`
+	ArtistName string `json:"artistName"`
+	SpotifyID  string `json:"spotifyId"`
+}
+
+type resetPayload struct {
+	Version string `json:"version"`
+}
+
+// eventHub keeps the recent events and wakes streams when one is published.
+// A nil hub drops everything.
+type eventHub struct {
+	mu     sync.Mutex
+	nextID uint64
+	events []streamEvent
+	// wake is closed and replaced on every publish.
+	wake chan struct{}
+	// links remembers the Spotify artist matched to each Groupie artist.
+	links     map[int]string
+	heartbeat time.Duration
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		nextID:    1,
+		wake:      make(chan struct{}),
+		links:     make(map[int]string),
+		heartbeat: defaultStreamHeartbeat,
+	}
+}
+
+func (h *eventHub) publish(typ string, artistID int, country string, payload interface{}) {
+	if h == nil {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("encode %s event: %v", typ, err)
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, streamEvent{ID: h.nextID, Type: typ, ArtistID: artistID, Country: country, Data: data})
+	h.nextID++
+	if extra := len(h.events) - maxStreamEvents; extra > 0 {
+		h.events = append([]streamEvent(nil), h.events[extra:]...)
+	}
+	close(h.wake)
+	h.wake = make(chan struct{})
+}
+
+// publishChange announces a data refresh. Unchanged data publishes nothing.
+func (h *eventHub) publishChange(change dataChange) {
+	if h == nil || !change.Changed {
+		return
+	}
+	sent := 0
+	for _, group := range []struct {
+		typ    string
+		events []Event
+	}{{streamConcertAdded, change.Added}, {streamConcertRemoved, change.Removed}} {
+		for _, ev := range group.events {
+			if sent == maxConcertEvents {
+				break
+			}
+			h.publish(group.typ, ev.ArtistID, ev.Country, ev)
+			sent++
+		}
+	}
+	h.publish(streamRefresh, 0, "", refreshPayload{
+		Version:   change.Version,
+		FetchedAt: change.FetchedAt,
+		Added:     len(change.Added),
+		Removed:   len(change.Removed),
+		Truncated: sent < len(change.Added)+len(change.Removed),
+	})
+}
+
+// link records that a Groupie artist matches a Spotify artist and publishes
+// the link the first time it is seen or when it changes.
+func (h *eventHub) link(art ArtistWithMeta, spotifyID string) {
+	if h == nil || spotifyID == "" {
+		return
+	}
+	h.mu.Lock()
+	known := h.links[art.ID] == spotifyID
+	h.links[art.ID] = spotifyID
+	h.mu.Unlock()
+	if !known {
+		h.publish(streamSpotifyLinked, art.ID, "", spotifyLinkPayload{
+			ArtistID: art.ID, ArtistSlug: art.Slug, ArtistName: art.Name, SpotifyID: spotifyID,
+		})
+	}
+}
+
+// since returns the events published after id, whether events between id and
+// the oldest kept one were lost, and a channel closed on the next publish.
+func (h *eventHub) since(id uint64) ([]streamEvent, bool, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// An ID from the future comes from before a restart: everything is lost.
+	if id >= h.nextID {
+		return nil, id > 0, h.wake
+	}
+	lost := len(h.events) > 0 && id+1 < h.events[0].ID
+	i := len(h.events)
+	for i > 0 && h.events[i-1].ID > id {
+		i--
+	}
+	return append([]streamEvent(nil), h.events[i:]...), lost, h.wake
+}
+
+// lastID returns the ID of the newest event, 0 if none.
+func (h *eventHub) lastID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.nextID - 1
+}
+
+// linkSpotifyMatches links the Spotify results whose name matches a Groupie
+// Tracker artist.
+func (a *App) linkSpotifyMatches(results []SpotifyArtist) {
+	if a.stream == nil || len(results) == 0 {
+		return
+	}
+	byName := make(map[string]ArtistWithMeta)
+	for _, art := range a.cache.ArtistsWithMeta() {
+		byName[normalizeText(art.Name)] = art
+	}
+	for _, sa := range results {
+		if art, ok := byName[normalizeText(sa.Name)]; ok {
+			a.stream.link(art, sa.ID)
+		}
+	}
+}
+
+// streamFilter selects the events a client asked for.
+type streamFilter struct {
+	Types     map[string]bool
+	Artists   map[int]bool
+	Countries map[string]bool
+}
+
+// Match reports whether ev passes the filter. Events without an artist or a
+// country, such as refresh, pass the topic filters.
+func (f streamFilter) Match(ev streamEvent) bool {
+	if f.Types != nil && !f.Types[ev.Type] {
+		return false
+	}
+	if f.Artists != nil && ev.ArtistID != 0 && !f.Artists[ev.ArtistID] {
+		return false
+	}
+	if f.Countries != nil && ev.Country != "" && !f.Countries[normalizeText(ev.Country)] {
+		return false
+	}
+	return true
+}
+
+func (a *App) parseStreamFilter(r *http.Request) (streamFilter, error) {
+	var f streamFilter
+	q := r.URL.Query()
+	if raw := strings.TrimSpace(q.Get("types")); raw != "" {
+		f.Types = make(map[string]bool)
+		for _, typ := range strings.Split(raw, ",") {
+			typ = strings.TrimSpace(typ)
+			if !containsString(streamTypes, typ) {
+				return f, newParamError("types", codeInvalidEventType, typ)
+			}
+			f.Types[typ] = true
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("artist")); raw != "" {
+		f.Artists = make(map[int]bool)
+		artists := a.cache.ArtistsWithMeta()
+		for _, key := range strings.Split(raw, ",") {
+			art, ok := findArtist(artists, strings.TrimSpace(key))
+			if !ok {
+				return f, newParamError("artist", codeUnknownArtist, strings.TrimSpace(key))
+			}
+			f.Artists[art.ID] = true
+		}
+	}
+	if raw := strings.TrimSpace(q.Get("country")); raw != "" {
+		f.Countries = make(map[string]bool)
+		for _, country := range strings.Split(raw, ",") {
+			f.Countries[normalizeText(country)] = true
+		}
+	}
+	return f, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// lastEventID reads the resume point from Last-Event-ID, or from the
+// last_event_id parameter for clients that cannot set headers.
+func lastEventID(r *http.Request) (uint64, bool) {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		raw = strings.TrimSpace(r.URL.Query().Get("last_event_id"))
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	return id, err == nil
+}
+
+// handleStream serves /api/stream as Server-Sent Events. A new client starts
+// at the newest event; a resuming one first gets what it missed, or a reset
+// event when that fell out of the buffer.
+func (a *App) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	if a.stream == nil {
+		writeProblem(w, r, codeUnavailable)
+		return
+	}
+	a.ensureCache(r.Context())
+	filter, err := a.parseStreamFilter(r)
+	if err != nil {
+		writeParamError(w, r, err)
+		return
+	}
+	last, resuming := lastEventID(r)
+	if !resuming {
+		last = a.stream.lastID()
+	}
+
+	rc := http.NewResponseController(w)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	write := func(format string, args ...interface{}) bool {
+		// Each write gets its own deadline instead of the server's WriteTimeout.
+		if err := rc.SetWriteDeadline(time.Now().Add(streamWriteWindow)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !write("retry: %d\n\n", streamRetry.Milliseconds()) {
+		return
+	}
+
+	heartbeat := time.NewTicker(a.stream.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		events, lost, wake := a.stream.since(last)
+		if lost {
+			data, _ := json.Marshal(resetPayload{Version: a.cache.Version()})
+			if !write("event: %s\ndata: %s\n\n", streamReset, data) {
+				return
+			}
+		}
+		for _, ev := range events {
+			last = ev.ID
+			if !filter.Match(ev) {
+				continue
+			}
+			if !write("id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, ev.Data) {
+				return
+			}
+		}
+		if lost && len(events) == 0 {
+			// Later events continue from the hub's numbering.
+			last = a.stream.lastID()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			if !write(": heartbeat\n\n") {
+				return
+			}
+		}
+	}
+}
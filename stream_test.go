@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseMessage struct {
+	ID, Event, Data string
+	Heartbeat       bool
+}
+
+// openStream connects to /api/stream on a server whose WriteTimeout is far
+// shorter than the test, and returns the parsed messages.
+func openStream(t *testing.T, app *App, query string, header map[string]string) <-chan sseMessage {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(app.routes())
+	srv.Config.WriteTimeout = 150 * time.Millisecond
+	srv.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+streamPath+query, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	out := make(chan sseMessage, 64)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		var msg sseMessage
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if msg != (sseMessage{}) {
+					out <- msg
+				}
+				msg = sseMessage{}
+			case strings.HasPrefix(line, ":"):
+				msg.Heartbeat = true
+			case strings.HasPrefix(line, "id: "):
+				msg.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				msg.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				msg.Data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return out
+}
+
+// nextEvent skips heartbeats and returns the next event.
+func nextEvent(t *testing.T, messages <-chan sseMessage) sseMessage {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				t.Fatalf("stream closed")
+			}
+			if msg.Event != "" {
+				return msg
+			}
+		case <-timeout:
+			t.Fatalf("no event received")
+		}
+	}
+}
+
+func streamTestApp() *App {
+	app := graphQLTestApp()
+	app.stream = newEventHub()
+	app.stream.heartbeat = 40 * time.Millisecond
+	return app
+}
+
+// withConcert returns the test data plus one concert.
+func withConcert(location, date string) DataBundle {
+	data := graphQLTestApp().cache.Snapshot()
+	data.Relations[1].DatesLocations[location] = []string{date}
+	return data
+}
+
+func TestStreamOutlivesWriteTimeout(t *testing.T) {
+	app := streamTestApp()
+	messages := openStream(t, app, "?country=germany", nil)
+
+	// Longer than the server's WriteTimeout, with heartbeats in between.
+	time.Sleep(400 * time.Millisecond)
+	app.setData(withConcert("munich-germany", "01-05-2030"))
+
+	added := nextEvent(t, messages)
+	if added.Event != streamConcertAdded || !strings.Contains(added.Data, `"city":"Munich"`) || added.ID != "1" {
+		t.Fatalf("unexpected event %+v", added)
+	}
+	refresh := nextEvent(t, messages)
+	if refresh.Event != streamRefresh || !strings.Contains(refresh.Data, `"added":1`) {
+		t.Fatalf("unexpected event %+v", refresh)
+	}
+
+	// Concerts outside the country filter are skipped.
+	app.setData(withConcert("tokyo-japan", "02-05-2030"))
+	for _, want := range []string{streamConcertRemoved, streamRefresh} {
+		if msg := nextEvent(t, messages); msg.Event != want {
+			t.Fatalf("expected %s, got %+v", want, msg)
+		}
+	}
+}
+
+func TestStreamResumesFromLastEventID(t *testing.T) {
+	app := streamTestApp()
+	app.setData(withConcert("munich-germany", "01-05-2030"))
+	app.setData(withConcert("tokyo-japan", "02-05-2030"))
+
+	messages := openStream(t, app, "?types=refresh", map[string]string{"Last-Event-ID": "2"})
+	msg := nextEvent(t, messages)
+	if msg.Event != streamRefresh || msg.ID != "5" {
+		t.Fatalf("expected the second refresh, got %+v", msg)
+	}
+
+	// An ID the hub never issued predates a restart: the client must reload.
+	messages = openStream(t, app, "", map[string]string{"Last-Event-ID": "999"})
+	if msg := nextEvent(t, messages); msg.Event != streamReset {
+		t.Fatalf("expected reset, got %+v", msg)
+	}
+}
+
+func TestEventHubBuffer(t *testing.T) {
+	hub := newEventHub()
+	for i := 0; i < maxStreamEvents+10; i++ {
+		hub.publish(streamRefresh, 0, "", refreshPayload{})
+	}
+	events, lost, _ := hub.since(0)
+	if !lost || len(events) != maxStreamEvents || events[0].ID != 11 {
+		t.Fatalf("expected the last %d events and a gap, got %d from %d (lost %v)", maxStreamEvents, len(events), events[0].ID, lost)
+	}
+	events, lost, _ = hub.since(hub.lastID() - 1)
+	if lost || len(events) != 1 {
+		t.Fatalf("expected one event without gap, got %d (lost %v)", len(events), lost)
+	}
+}
+
+func TestStreamSpotifyLinks(t *testing.T) {
+	app := streamTestApp()
+	app.linkSpotifyMatches([]SpotifyArtist{{ID: "sp-queen", Name: "QUEEN"}, {ID: "sp-other", Name: "Someone Else"}})
+	app.linkSpotifyMatches([]SpotifyArtist{{ID: "sp-queen", Name: "Queen"}})
+	events, _, _ := app.stream.since(0)
+	if len(events) != 1 || events[0].Type != streamSpotifyLinked || events[0].ArtistID != 1 || !strings.Contains(string(events[0].Data), `"spotifyId":"sp-queen"`) {
+		t.Fatalf("expected one link event, got %+v", events)
+	}
+}
+
+func TestStreamRejectsBadFilters(t *testing.T) {
+	app := streamTestApp()
+	for query, code := range map[string]string{
+		"?types=concert.moved": codeInvalidEventType,
+		"?artist=nobody":       codeUnknownArtist,
+	} {
+		rr, p := serveProblem(t, app, http.MethodGet, streamPath+query, nil)
+		if rr.Code != http.StatusBadRequest || p.Code != code {
+			t.Errorf("%s: unexpected %d %+v", query, rr.Code, p)
+		}
+	}
+}
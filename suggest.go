@@ -0,0 +1,217 @@
+package main
+
+import (
+	"container/heap"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Suggestion types returned by /api/suggest.
+const (
+	suggestArtist  = "artist"
+	suggestMember  = "member"
+	suggestCity    = "city"
+	suggestCountry = "country"
+	suggestYear    = "year"
+)
+
+// suggestBudget is the latency target for a suggestion lookup. Lookups over it are logged.
+const suggestBudget = 5 * time.Millisecond
+
+// Suggestion is one autocomplete entry. MatchStart and MatchEnd are character
+// offsets into Value delimiting the part that matched the query.
+type Suggestion struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	ID         string `json:"id,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	MatchStart int    `json:"matchStart"`
+	MatchEnd   int    `json:"matchEnd"`
+	weight     int
+}
+
+// suggestKey is one searchable suffix of a suggestion's folded value. Every
+// suggestion gets a key per word so "merc" finds "Freddie Mercury".
+type suggestKey struct {
+	key   string
+	entry int
+	at    int
+}
+
+type suggestEntry struct {
+	suggestion Suggestion
+	offsets    []int
+}
+
+// suggestIndex is a sorted prefix table built once per cache refresh.
+type suggestIndex struct {
+	entries []suggestEntry
+	keys    []suggestKey
+}
+
+var suggestTypeOrder = map[string]int{
+	suggestArtist:  0,
+	suggestMember:  1,
+	suggestCity:    2,
+	suggestCountry: 3,
+	suggestYear:    4,
+}
+
+func (idx *suggestIndex) add(s Suggestion) {
+	folded, offsets := normalizeWithOffsets(s.Value)
+	if folded == "" {
+		return
+	}
+	entry := len(idx.entries)
+	idx.entries = append(idx.entries, suggestEntry{suggestion: s, offsets: offsets})
+	for i := 0; i < len(folded); i++ {
+		if i == 0 || folded[i-1] == ' ' {
+			idx.keys = append(idx.keys, suggestKey{key: folded[i:], entry: entry, at: i})
+		}
+	}
+}
+
+// buildSuggestIndex collects artists, members, cities, countries and years.
+func buildSuggestIndex(bundle DataBundle) *suggestIndex {
+	idx := &suggestIndex{}
+	artists := mergeArtists(bundle)
+	events := buildEvents(bundle.Artists, bundle.Relations)
+
+	years := make(map[int]int)
+	for _, art := range artists {
+		idx.add(Suggestion{Type: suggestArtist, Value: art.Name, ID: art.Slug, weight: len(art.DateList)})
+		if art.CreationDate > 0 {
+			years[art.CreationDate]++
+		}
+		if art.FirstAlbumYear > 0 {
+			years[art.FirstAlbumYear]++
+		}
+	}
+	for _, m := range buildMembers(bundle.Artists) {
+		detail := ""
+		if len(m.Bands) > 0 {
+			detail = m.Bands[0].ArtistName
+		}
+		idx.add(Suggestion{Type: suggestMember, Value: m.Name, ID: m.ID, Detail: detail, weight: len(m.Bands)})
+	}
+	for _, c := range summarizeCities(events) {
+		idx.add(Suggestion{Type: suggestCity, Value: c.Name, ID: c.Slug, Detail: c.Country, weight: c.ConcertCount})
+	}
+	for _, c := range summarizeCountries(events) {
+		idx.add(Suggestion{Type: suggestCountry, Value: c.Name, ID: c.Code, weight: c.ConcertCount})
+	}
+	for _, ev := range events {
+		years[ev.Date.Year()]++
+	}
+	for year, n := range years {
+		value := strconv.Itoa(year)
+		idx.add(Suggestion{Type: suggestYear, Value: value, ID: value, weight: n})
+	}
+
+	sort.Slice(idx.keys, func(i, j int) bool {
+		return idx.keys[i].key < idx.keys[j].key
+	})
+	return idx
+}
+
+// suggestCandidate is the best key of an entry for the current query.
+type suggestCandidate struct {
+	key   suggestKey
+	start bool
+}
+
+// suggestRanking keeps the best limit candidates of a lookup as a heap whose
+// root is the worst of them, so every prefix match is ranked without sorting
+// them all.
+type suggestRanking struct {
+	idx   *suggestIndex
+	items []suggestCandidate
+	pos   map[int]int // entry -> index in items
+}
+
+// better orders matches at the start of a value first, then by type,
+// popularity and value.
+func (r *suggestRanking) better(a, b suggestCandidate) bool {
+	if a.start != b.start {
+		return a.start
+	}
+	sa, sb := r.idx.entries[a.key.entry].suggestion, r.idx.entries[b.key.entry].suggestion
+	if suggestTypeOrder[sa.Type] != suggestTypeOrder[sb.Type] {
+		return suggestTypeOrder[sa.Type] < suggestTypeOrder[sb.Type]
+	}
+	if sa.weight != sb.weight {
+		return sa.weight > sb.weight
+	}
+	return sa.Value < sb.Value
+}
+
+func (r *suggestRanking) Len() int           { return len(r.items) }
+func (r *suggestRanking) Less(i, j int) bool { return r.better(r.items[j], r.items[i]) }
+func (r *suggestRanking) Swap(i, j int) {
+	r.items[i], r.items[j] = r.items[j], r.items[i]
+	r.pos[r.items[i].key.entry] = i
+	r.pos[r.items[j].key.entry] = j
+}
+func (r *suggestRanking) Push(x interface{}) {
+	c := x.(suggestCandidate)
+	r.pos[c.key.entry] = len(r.items)
+	r.items = append(r.items, c)
+}
+func (r *suggestRanking) Pop() interface{} {
+	c := r.items[len(r.items)-1]
+	r.items = r.items[:len(r.items)-1]
+	delete(r.pos, c.key.entry)
+	return c
+}
+
+// offer adds c unless limit better candidates are already held. An entry
+// matched by several words keeps its start match, or else its first word.
+func (r *suggestRanking) offer(c suggestCandidate, limit int) {
+	if i, ok := r.pos[c.key.entry]; ok {
+		if prev := r.items[i]; !prev.start && (c.start || c.key.at < prev.key.at) {
+			r.items[i] = c
+			heap.Fix(r, i)
+		}
+		return
+	}
+	if len(r.items) < limit {
+		heap.Push(r, c)
+		return
+	}
+	if r.better(c, r.items[0]) {
+		delete(r.pos, r.items[0].key.entry)
+		r.items[0] = c
+		r.pos[c.key.entry] = 0
+		heap.Fix(r, 0)
+	}
+}
+
+// Suggest returns at most limit suggestions whose words start with query.
+// Matches at the start of a value rank first, then by type and popularity.
+func (idx *suggestIndex) Suggest(query string, limit int) []Suggestion {
+	q := normalizeText(query)
+	if idx == nil || q == "" || limit <= 0 {
+		return []Suggestion{}
+	}
+
+	ranking := &suggestRanking{idx: idx, pos: make(map[int]int, limit)}
+	pos := sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i].key >= q })
+	for ; pos < len(idx.keys) && strings.HasPrefix(idx.keys[pos].key, q); pos++ {
+		k := idx.keys[pos]
+		ranking.offer(suggestCandidate{key: k, start: k.at == 0}, limit)
+	}
+	ranked := ranking.items
+	sort.Slice(ranked, func(i, j int) bool { return ranking.better(ranked[i], ranked[j]) })
+
+	out := make([]Suggestion, 0, len(ranked))
+	for _, c := range ranked {
+		entry := idx.entries[c.key.entry]
+		s := entry.suggestion
+		s.MatchStart = entry.offsets[c.key.at]
+		s.MatchEnd = entry.offsets[c.key.at+len(q)-1] + 1
+		out = append(out, s)
+	}
+	return out
+}
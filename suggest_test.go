@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSuggestPrefixAndSpans(t *testing.T) {
+	idx := buildSuggestIndex(DataBundle{
+		Artists: []Artist{
+			{ID: 1, Name: "Queen", Members: []string{"Freddie Mercury"}, CreationDate: 1970},
+			{ID: 2, Name: "Mötley Crüe", Members: []string{"Vince Neil"}, CreationDate: 1981},
+		},
+		Relations: []Relation{
+			{ID: 1, DatesLocations: map[string][]string{"mexico_city-mexico": {"01-01-1981"}}},
+		},
+	})
+
+	got := idx.Suggest("merc", 8)
+	if len(got) != 1 || got[0].Type != suggestMember || got[0].Value != "Freddie Mercury" {
+		t.Fatalf("unexpected suggestions %+v", got)
+	}
+	if got[0].MatchStart != 8 || got[0].MatchEnd != 12 {
+		t.Fatalf("unexpected span %d-%d", got[0].MatchStart, got[0].MatchEnd)
+	}
+
+	got = idx.Suggest("crue", 8)
+	if len(got) != 1 || got[0].MatchStart != 7 || got[0].MatchEnd != 11 {
+		t.Fatalf("expected accent-folded span on Mötley Crüe, got %+v", got)
+	}
+
+	got = idx.Suggest("me", 8)
+	if len(got) < 3 || got[0].Type != suggestCity || got[1].Type != suggestCountry {
+		t.Fatalf("expected leading matches (city, country) before word matches, got %+v", got)
+	}
+
+	got = idx.Suggest("198", 8)
+	if len(got) != 1 || got[0].Type != suggestYear || got[0].Value != "1981" {
+		t.Fatalf("expected year suggestion, got %+v", got)
+	}
+}
+
+func TestSuggestRanksEveryPrefixMatch(t *testing.T) {
+	artists := make([]Artist, 0, 601)
+	for i := 0; i < 600; i++ {
+		artists = append(artists, Artist{ID: i + 1, Name: fmt.Sprintf("Band %03d", i)})
+	}
+	// Alphabetically past the first 600 keys, but the only one with concerts.
+	artists = append(artists, Artist{ID: 601, Name: "Bz Popular"})
+	idx := buildSuggestIndex(DataBundle{
+		Artists: artists,
+		Dates:   []DatesIndex{{ID: 601, Dates: []string{"*01-01-2020", "02-01-2020"}}},
+	})
+
+	got := idx.Suggest("b", 3)
+	if len(got) != 3 || got[0].Value != "Bz Popular" || got[1].Value != "Band 000" || got[2].Value != "Band 001" {
+		t.Fatalf("expected the popular artist first, got %+v", got)
+	}
+}
+
+func suggestBenchIndex() *suggestIndex {
+	artists := make([]Artist, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		artists = append(artists, Artist{ID: i + 1, Name: fmt.Sprintf("Band %d", i), Members: []string{fmt.Sprintf("Member %d", i)}})
+	}
+	return buildSuggestIndex(DataBundle{Artists: artists})
+}
+
+// TestSuggestLatency checks a broad lookup against ten times suggestBudget,
+// loose enough for slow or instrumented builds.
+func TestSuggestLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("latency check skipped in short mode")
+	}
+	idx := suggestBenchIndex()
+	const runs = 100
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		idx.Suggest("b", 8)
+	}
+	if avg := time.Since(start) / runs; avg > 10*suggestBudget {
+		t.Fatalf("suggest took %v on average, budget %v", avg, suggestBudget)
+	}
+}
+
+// BenchmarkSuggest measures a broad one-letter lookup over 2000 artists; compare
+// ns/op with suggestBudget.
+func BenchmarkSuggest(b *testing.B) {
+	idx := suggestBenchIndex()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Suggest("b", 8)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	// Embed the IANA database so zone lookups work on hosts without tzdata (e.g. Windows).
+	_ "time/tzdata"
+)
+
+const defaultTimeZone = "UTC"
+
+// countryTimeZones maps upstream country slugs to the zone used when the city is unknown.
+var countryTimeZones = map[string]string{
+	"argentina":            "America/Argentina/Buenos_Aires",
+	"australia":            "Australia/Sydney",
+	"austria":              "Europe/Vienna",
+	"belarus":              "Europe/Minsk",
+	"belgium":              "Europe/Brussels",
+	"brazil":               "America/Sao_Paulo",
+	"canada":               "America/Toronto",
+	"chile":                "America/Santiago",
+	"china":                "Asia/Shanghai",
+	"colombia":             "America/Bogota",
+	"costa_rica":           "America/Costa_Rica",
+	"czech_republic":       "Europe/Prague",
+	"czechia":              "Europe/Prague",
+	"denmark":              "Europe/Copenhagen",
+	"finland":              "Europe/Helsinki",
+	"france":               "Europe/Paris",
+	"french_polynesia":     "Pacific/Tahiti",
+	"germany":              "Europe/Berlin",
+	"greece":               "Europe/Athens",
+	"hungary":              "Europe/Budapest",
+	"india":                "Asia/Kolkata",
+	"indonesia":            "Asia/Jakarta",
+	"ireland":              "Europe/Dublin",
+	"italy":                "Europe/Rome",
+	"japan":                "Asia/Tokyo",
+	"mexico":               "America/Mexico_City",
+	"netherlands":          "Europe/Amsterdam",
+	"netherlands_antilles": "America/Curacao",
+	"new_caledonia":        "Pacific/Noumea",
+	"new_zealand":          "Pacific/Auckland",
+	"norway":               "Europe/Oslo",
+	"peru":                 "America/Lima",
+	"philippine":           "Asia/Manila",
+	"philippines":          "Asia/Manila",
+	"poland":               "Europe/Warsaw",
+	"portugal":             "Europe/Lisbon",
+	"qatar":                "Asia/Qatar",
+	"romania":              "Europe/Bucharest",
+	"russia":               "Europe/Moscow",
+	"saudi_arabia":         "Asia/Riyadh",
+	"slovakia":             "Europe/Bratislava",
+	"south_africa":         "Africa/Johannesburg",
+	"south_korea":          "Asia/Seoul",
+	"spain":                "Europe/Madrid",
+	"sweden":               "Europe/Stockholm",
+	"switzerland":          "Europe/Zurich",
+	"taiwan":               "Asia/Taipei",
+	"thailand":             "Asia/Bangkok",
+	"uk":                   "Europe/London",
+	"united_arab_emirates": "Asia/Dubai",
+	"usa":                  "America/New_York",
+}
+
+// cityTimeZones refines countries that span several zones. Keys are "city-country" slugs;
+// the upstream API sometimes uses a state name in place of the city.
+var cityTimeZones = map[string]string{
+	"alabama-usa":          "America/Chicago",
+	"arizona-usa":          "America/Phoenix",
+	"california-usa":       "America/Los_Angeles",
+	"chicago-usa":          "America/Chicago",
+	"colorado-usa":         "America/Denver",
+	"dallas-usa":           "America/Chicago",
+	"denver-usa":           "America/Denver",
+	"houston-usa":          "America/Chicago",
+	"illinois-usa":         "America/Chicago",
+	"las_vegas-usa":        "America/Los_Angeles",
+	"los_angeles-usa":      "America/Los_Angeles",
+	"minnesota-usa":        "America/Chicago",
+	"missouri-usa":         "America/Chicago",
+	"nevada-usa":           "America/Los_Angeles",
+	"oregon-usa":           "America/Los_Angeles",
+	"phoenix-usa":          "America/Phoenix",
+	"san_francisco-usa":    "America/Los_Angeles",
+	"seattle-usa":          "America/Los_Angeles",
+	"texas-usa":            "America/Chicago",
+	"utah-usa":             "America/Denver",
+	"washington-usa":       "America/Los_Angeles",
+	"hawaii-usa":           "Pacific/Honolulu",
+	"alaska-usa":           "America/Anchorage",
+	"alberta-canada":       "America/Edmonton",
+	"calgary-canada":       "America/Edmonton",
+	"edmonton-canada":      "America/Edmonton",
+	"vancouver-canada":     "America/Vancouver",
+	"winnipeg-canada":      "America/Winnipeg",
+	"montreal-canada":      "America/Toronto",
+	"quebec-canada":        "America/Toronto",
+	"adelaide-australia":   "Australia/Adelaide",
+	"brisbane-australia":   "Australia/Brisbane",
+	"melbourne-australia":  "Australia/Melbourne",
+	"perth-australia":      "Australia/Perth",
+	"manaus-brazil":        "America/Manaus",
+	"tijuana-mexico":       "America/Tijuana",
+	"monterrey-mexico":     "America/Monterrey",
+	"yekaterinburg-russia": "Asia/Yekaterinburg",
+	"novosibirsk-russia":   "Asia/Novosibirsk",
+}
+
+// locationTimeZone resolves an upstream location slug to an IANA zone name.
+// Unknown locations fall back to UTC.
+func locationTimeZone(slug string) string {
+	key := strings.ToLower(strings.TrimSpace(slug))
+	if tz, ok := cityTimeZones[key]; ok {
+		return tz
+	}
+	parts := strings.Split(key, "-")
+	if tz, ok := countryTimeZones[parts[len(parts)-1]]; ok {
+		return tz
+	}
+	return defaultTimeZone
+}
+
+var zoneCache sync.Map
+
+// loadZone loads a zone by name, falling back to UTC when it is unknown.
+// Loaded zones are memoised since time.LoadLocation parses tzdata on every call.
+func loadZone(name string) *time.Location {
+	if cached, ok := zoneCache.Load(name); ok {
+		return cached.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = time.UTC
+	}
+	zoneCache.Store(name, loc)
+	return loc
+}
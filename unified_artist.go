@@ -13,26 +13,32 @@ const (
 // UnifiedArtist represents the harmonised shape returned by the search endpoint,
 // regardless of whether data comes from the Groupie Tracker API or Spotify.
 type UnifiedArtist struct {
-	ID           string   `json:"id"`
-	Name         string   `json:"name"`
-	ImageURL     string   `json:"image_url"`
-	Source       string   `json:"source"`
-	CreationDate int      `json:"creationDate,omitempty"`
-	FirstAlbum   string   `json:"firstAlbum,omitempty"`
-	Members      []string `json:"members,omitempty"`
-	Genres       []string `json:"genres,omitempty"`
-	Popularity   int      `json:"popularity,omitempty"`
+	ID             string   `json:"id"`
+	Slug           string   `json:"slug,omitempty"`
+	Name           string   `json:"name"`
+	ImageURL       string   `json:"image_url"`
+	Source         string   `json:"source"`
+	CreationDate   int      `json:"creationDate,omitempty"`
+	FirstAlbum     string   `json:"firstAlbum,omitempty"`
+	FirstAlbumYear int      `json:"firstAlbumYear,omitempty"`
+	Members        []string `json:"members,omitempty"`
+	Genres         []string `json:"genres,omitempty"`
+	Popularity     int      `json:"popularity,omitempty"`
+	MatchScore     *float64 `json:"matchScore,omitempty"`
 }
 
 func toUnifiedGroupie(a ArtistWithMeta) UnifiedArtist {
 	return UnifiedArtist{
-		ID:           strconv.Itoa(a.ID),
-		Name:         a.Name,
-		ImageURL:     a.Image,
-		Source:       sourceGroupie,
-		CreationDate: a.CreationDate,
-		FirstAlbum:   a.FirstAlbum,
-		Members:      append([]string(nil), a.Members...),
+		ID:             strconv.Itoa(a.ID),
+		Slug:           a.Slug,
+		Name:           a.Name,
+		ImageURL:       a.Image,
+		Source:         sourceGroupie,
+		CreationDate:   a.CreationDate,
+		FirstAlbum:     a.FirstAlbum,
+		FirstAlbumYear: a.FirstAlbumYear,
+		MatchScore:     a.MatchScore,
+		Members:        append([]string(nil), a.Members...),
 	}
 }
 